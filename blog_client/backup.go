@@ -0,0 +1,525 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/k-yomo/blog_with_grpc/backuppb"
+	"github.com/k-yomo/blog_with_grpc/blogclient"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const backupVersion = 1
+
+var backupFormats = []string{"jsonl", "protobuf"}
+
+// protobufMagic starts a protobuf backup, so its first length prefix is never mistaken for the '{' of JSON Lines
+const protobufMagic = "BLOGPB1\n"
+
+// maxRecordSize bounds a length prefix, a larger one means the file is corrupt
+const maxRecordSize = 64 << 20
+
+// recordWriter appends records to a backup file
+type recordWriter interface {
+	write(*backuppb.Record) error
+}
+
+// jsonlWriter writes one JSON object per line
+type jsonlWriter struct {
+	w io.Writer
+}
+
+func (w *jsonlWriter) write(r *backuppb.Record) error {
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(proto.MessageV2(r))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.w, "%s\n", b)
+	return err
+}
+
+// delimitedWriter writes each record as a uvarint length followed by its protobuf encoding
+type delimitedWriter struct {
+	w io.Writer
+}
+
+func (w *delimitedWriter) write(r *backuppb.Record) error {
+	b, err := proto.Marshal(r)
+	if err != nil {
+		return err
+	}
+	prefix := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(prefix, uint64(len(b)))
+	if _, err := w.w.Write(prefix[:n]); err != nil {
+		return err
+	}
+	_, err = w.w.Write(b)
+	return err
+}
+
+func newRecordWriter(format string, w io.Writer) (recordWriter, error) {
+	switch format {
+	case "jsonl":
+		return &jsonlWriter{w: w}, nil
+	case "protobuf":
+		return &delimitedWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown backup format %q, use one of %s", format, strings.Join(backupFormats, ", "))
+}
+
+// recordReader reads the records of a backup file in either format
+type recordReader struct {
+	r     *bufio.Reader
+	jsonl bool
+	// offset is the end of the last record read
+	offset int64
+}
+
+// newRecordReader tells the format from the start of the file: protobufMagic or the '{' of JSON Lines
+func newRecordReader(r io.Reader) (*recordReader, string, error) {
+	rr := &recordReader{r: bufio.NewReader(r)}
+	start, err := rr.r.Peek(len(protobufMagic))
+	if len(start) == 0 && err == io.EOF {
+		return nil, "", errors.New("the backup is empty")
+	}
+	if err != nil && err != io.EOF {
+		return nil, "", err
+	}
+	switch {
+	case string(start) == protobufMagic:
+		rr.r.Discard(len(protobufMagic))
+		rr.offset = int64(len(protobufMagic))
+		return rr, "protobuf", nil
+	case start[0] == '{':
+		rr.jsonl = true
+		return rr, "jsonl", nil
+	}
+	return nil, "", errors.New("the file is not a backup")
+}
+
+// next returns io.EOF after the last record and io.ErrUnexpectedEOF for a record cut short
+func (rr *recordReader) next() (*backuppb.Record, error) {
+	var b []byte
+	n := 0
+	if rr.jsonl {
+		line, err := rr.r.ReadBytes('\n')
+		if err == io.EOF && len(line) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, n = line, len(line)
+	} else {
+		size, err := binary.ReadUvarint(rr.r)
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if size > maxRecordSize {
+			return nil, fmt.Errorf("record at byte %d is %d bytes long, the backup is corrupt", rr.offset, size)
+		}
+		b = make([]byte, size)
+		if _, err := io.ReadFull(rr.r, b); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		n = uvarintLen(size) + len(b)
+	}
+
+	record := &backuppb.Record{}
+	var err error
+	if rr.jsonl {
+		err = protojson.Unmarshal(b, proto.MessageV2(record))
+	} else {
+		err = proto.Unmarshal(b, record)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid record at byte %d: %v", rr.offset, err)
+	}
+	rr.offset += int64(n)
+	return record, nil
+}
+
+func uvarintLen(v uint64) int {
+	return binary.PutUvarint(make([]byte, binary.MaxVarintLen64), v)
+}
+
+// blogHasher computes the checksum of the trailer
+type blogHasher struct {
+	h     hash.Hash
+	count int64
+}
+
+func newBlogHasher() *blogHasher {
+	return &blogHasher{h: sha256.New()}
+}
+
+func (bh *blogHasher) add(blog *blogpb.Blog) error {
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(blog); err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(buf.Bytes())))
+	bh.h.Write(size[:])
+	bh.h.Write(buf.Bytes())
+	bh.count++
+	return nil
+}
+
+func (bh *blogHasher) sum() string {
+	return hex.EncodeToString(bh.h.Sum(nil))
+}
+
+// snapshot and restore let a resumed backup continue the checksum from a checkpoint
+func (bh *blogHasher) snapshot() ([]byte, error) {
+	return bh.h.(encoding.BinaryMarshaler).MarshalBinary()
+}
+
+func (bh *blogHasher) restore(state []byte, count int64) error {
+	bh.count = count
+	return bh.h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state)
+}
+
+// backupState is where a resumed backup continues
+type backupState struct {
+	format string
+	offset int64
+	token  string
+	hasher *blogHasher
+}
+
+// readBackupState finds the last checkpoint of an interrupted backup, anything after it is written again
+func readBackupState(f *os.File) (*backupState, error) {
+	rr, format, err := newRecordReader(f)
+	if err != nil {
+		return nil, err
+	}
+	header, err := rr.next()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHeader(header); err != nil {
+		return nil, err
+	}
+
+	hasher := newBlogHasher()
+	state := &backupState{format: format, offset: rr.offset, hasher: hasher}
+	checkpoint, err := hasher.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	count := int64(0)
+	for {
+		record, err := rr.next()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case record.GetTrailer() != nil:
+			return nil, errors.New("the backup is already complete")
+		case record.GetBlog() != nil:
+			if err := hasher.add(record.GetBlog()); err != nil {
+				return nil, err
+			}
+		case record.GetCheckpoint() != nil:
+			if record.GetCheckpoint().GetCount() != hasher.count {
+				return nil, fmt.Errorf("checkpoint at byte %d counts %d blogs, %d were read", state.offset, record.GetCheckpoint().GetCount(), hasher.count)
+			}
+			if checkpoint, err = hasher.snapshot(); err != nil {
+				return nil, err
+			}
+			count = hasher.count
+			state.offset = rr.offset
+			state.token = record.GetCheckpoint().GetNextPageToken()
+		}
+	}
+	if err := hasher.restore(checkpoint, count); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func checkHeader(record *backuppb.Record) error {
+	if record.GetHeader() == nil {
+		return errors.New("the backup does not start with a header")
+	}
+	if v := record.GetHeader().GetVersion(); v != backupVersion {
+		return fmt.Errorf("backup version %d is not supported", v)
+	}
+	return nil
+}
+
+func runBackup(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("backup", "<file>")
+	format := fs.String("format", "jsonl", "file format: "+strings.Join(backupFormats, ", "))
+	resume := fs.Bool("resume", false, "continue an interrupted backup from its last checkpoint")
+	pageSize := fs.Int("page-size", 100, "blogs requested per call, a checkpoint is written after each page")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("exactly one file is required")
+	}
+	path := fs.Arg(0)
+
+	var (
+		f     *os.File
+		state *backupState
+		err   error
+	)
+	if *resume {
+		if f, err = os.OpenFile(path, os.O_RDWR, 0); err != nil {
+			return err
+		}
+		defer f.Close()
+		if state, err = readBackupState(f); err != nil {
+			return fmt.Errorf("cannot resume %s: %v", path, err)
+		}
+		if err := f.Truncate(state.offset); err != nil {
+			return err
+		}
+		if _, err := f.Seek(state.offset, io.SeekStart); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Resuming after %d blogs\n", state.hasher.count)
+	} else {
+		// an existing backup is never overwritten
+		if f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644); err != nil {
+			if os.IsExist(err) {
+				return fmt.Errorf("%s exists, use -resume to continue it", path)
+			}
+			return err
+		}
+		defer f.Close()
+		state = &backupState{format: *format, hasher: newBlogHasher()}
+	}
+
+	buf := bufio.NewWriter(f)
+	w, err := newRecordWriter(state.format, buf)
+	if err != nil {
+		return err
+	}
+	if !*resume {
+		if state.format == "protobuf" {
+			if _, err := buf.WriteString(protobufMagic); err != nil {
+				return err
+			}
+		}
+		header := &backuppb.Header{Version: backupVersion, CreateTime: ptypes.TimestampNow(), Server: c.Target()}
+		if err := w.write(&backuppb.Record{Header: header}); err != nil {
+			return err
+		}
+	}
+
+	token := state.token
+	for {
+		pageCtx, cancel := withCommandTimeout(ctx)
+		blogs, next, err := c.ListBlogs(pageCtx, int32(*pageSize), token)
+		cancel()
+		if err != nil {
+			buf.Flush()
+			return fmt.Errorf("backup interrupted after %d blogs, continue it with -resume: %v", state.hasher.count, err)
+		}
+		for _, blog := range blogs {
+			// rendered_html is output only and depends on the server's renderer, the content is enough to restore
+			blog.RenderedHtml = ""
+			if err := w.write(&backuppb.Record{Blog: blog}); err != nil {
+				return err
+			}
+			if err := state.hasher.add(blog); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			break
+		}
+		token = next
+		if err := w.write(&backuppb.Record{Checkpoint: &backuppb.Checkpoint{NextPageToken: next, Count: state.hasher.count}}); err != nil {
+			return err
+		}
+		// a checkpoint only counts once the blogs before it are on disk
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+	}
+
+	sum := state.hasher.sum()
+	if err := w.write(&backuppb.Record{Trailer: &backuppb.Trailer{Count: state.hasher.count, Sha256: sum}}); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Backed up %d blogs to %s, sha256 %s\n", state.hasher.count, path, sum)
+	return f.Close()
+}
+
+// verifyBackup checks the header, the checkpoints and the trailer of a complete backup
+func verifyBackup(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	rr, _, err := newRecordReader(f)
+	if err != nil {
+		return 0, err
+	}
+	header, err := rr.next()
+	if err != nil {
+		return 0, err
+	}
+	if err := checkHeader(header); err != nil {
+		return 0, err
+	}
+
+	hasher := newBlogHasher()
+	for {
+		record, err := rr.next()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, errors.New("the backup has no trailer, finish it with backup -resume")
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case record.GetBlog() != nil:
+			if err := hasher.add(record.GetBlog()); err != nil {
+				return 0, err
+			}
+		case record.GetCheckpoint() != nil:
+			if record.GetCheckpoint().GetCount() != hasher.count {
+				return 0, fmt.Errorf("checkpoint counts %d blogs, %d were read", record.GetCheckpoint().GetCount(), hasher.count)
+			}
+		case record.GetTrailer() != nil:
+			trailer := record.GetTrailer()
+			if trailer.GetCount() != hasher.count {
+				return 0, fmt.Errorf("trailer counts %d blogs, %d were read", trailer.GetCount(), hasher.count)
+			}
+			if trailer.GetSha256() != hasher.sum() {
+				return 0, errors.New("checksum mismatch, the backup is corrupt")
+			}
+			if _, err := rr.next(); err != io.EOF {
+				return 0, errors.New("records follow the trailer")
+			}
+			return hasher.count, nil
+		}
+	}
+}
+
+// restoreProgressEvery is how often the number of restored blogs is saved for -resume
+const restoreProgressEvery = 100
+
+func runRestore(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("restore", "<file>")
+	resume := fs.Bool("resume", false, "skip the blogs an interrupted restore of the file already wrote")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("exactly one file is required")
+	}
+	path := fs.Arg(0)
+	progressPath := path + ".restore-progress"
+
+	// nothing is written unless the whole file is intact
+	total, err := verifyBackup(path)
+	if err != nil {
+		return fmt.Errorf("cannot restore %s: %v", path, err)
+	}
+	skip := int64(0)
+	if *resume {
+		if b, err := ioutil.ReadFile(progressPath); err == nil {
+			if skip, err = strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64); err != nil {
+				return fmt.Errorf("invalid %s: %v", progressPath, err)
+			}
+			fmt.Fprintf(os.Stderr, "Resuming after %d of %d blogs\n", skip, total)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rr, _, err := newRecordReader(f)
+	if err != nil {
+		return err
+	}
+
+	var done, created, replaced int64
+	saveProgress := func() error {
+		return ioutil.WriteFile(progressPath, []byte(strconv.FormatInt(done, 10)+"\n"), 0644)
+	}
+	for {
+		record, err := rr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		blog := record.GetBlog()
+		if blog == nil {
+			continue
+		}
+		if done < skip {
+			done++
+			continue
+		}
+
+		callCtx, cancel := withCommandTimeout(ctx)
+		isNew, err := c.RestoreBlog(callCtx, blog)
+		cancel()
+		if err != nil {
+			if saveErr := saveProgress(); saveErr != nil {
+				return fmt.Errorf("restore interrupted after %d of %d blogs and its progress was not saved (%v), restore the file again: blog %s: %v", done, total, saveErr, blog.GetId(), err)
+			}
+			return fmt.Errorf("restore interrupted after %d of %d blogs, continue it with -resume: blog %s: %v", done, total, blog.GetId(), err)
+		}
+		done++
+		if isNew {
+			created++
+		} else {
+			replaced++
+		}
+		if done%restoreProgressEvery == 0 {
+			if err := saveProgress(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.Remove(progressPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// counts are of this run, blogs skipped by -resume were counted by the run that wrote them
+	fmt.Fprintf(os.Stderr, "Restored %d blogs from %s: %d created, %d replaced, %d skipped as restored before\n", created+replaced, path, created, replaced, skip)
+	return nil
+}
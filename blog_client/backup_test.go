@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/k-yomo/blog_with_grpc/backuppb"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testBlog(i int) *blogpb.Blog {
+	return &blogpb.Blog{Id: fmt.Sprintf("%024x", i), AuthorId: "author", Title: fmt.Sprintf("title %d", i), Content: "line\n\"quoted\""}
+}
+
+func blogRecord(i int) *backuppb.Record {
+	return &backuppb.Record{Blog: testBlog(i)}
+}
+
+func headerRecord() *backuppb.Record {
+	return &backuppb.Record{Header: &backuppb.Header{Version: backupVersion, Server: "localhost:50051"}}
+}
+
+func checkpointRecord(token string, count int64) *backuppb.Record {
+	return &backuppb.Record{Checkpoint: &backuppb.Checkpoint{NextPageToken: token, Count: count}}
+}
+
+// trailerRecord is the trailer of a backup of blogs 0 to count-1
+func trailerRecord(t *testing.T, count int) *backuppb.Record {
+	hasher := newBlogHasher()
+	for i := 0; i < count; i++ {
+		if err := hasher.add(testBlog(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &backuppb.Record{Trailer: &backuppb.Trailer{Count: hasher.count, Sha256: hasher.sum()}}
+}
+
+// writeTestBackup writes records the way runBackup does, cut is the number of bytes removed from the end,
+// offsets holds the end of each record in the file
+func writeTestBackup(t *testing.T, format string, records []*backuppb.Record, cut int) (path string, offsets []int64) {
+	var buf bytes.Buffer
+	if format == "protobuf" {
+		buf.WriteString(protobufMagic)
+	}
+	w, err := newRecordWriter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if err := w.write(r); err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, int64(buf.Len()))
+	}
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path = filepath.Join(dir, "blogs."+format)
+	if err := ioutil.WriteFile(path, buf.Bytes()[:buf.Len()-cut], 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, offsets
+}
+
+func TestReadBackupState(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []*backuppb.Record
+		cut     int
+		// resumeAfter is the index of the record the backup continues after
+		resumeAfter int
+		token       string
+		count       int64
+		wantErr     string
+	}{
+		{
+			name:        "no checkpoint yet",
+			records:     []*backuppb.Record{headerRecord(), blogRecord(0), blogRecord(1)},
+			resumeAfter: 0,
+		},
+		{
+			name:        "blogs after the last checkpoint are dropped",
+			records:     []*backuppb.Record{headerRecord(), blogRecord(0), blogRecord(1), checkpointRecord("b", 2), blogRecord(2), checkpointRecord("c", 3), blogRecord(3)},
+			resumeAfter: 5,
+			token:       "c",
+			count:       3,
+		},
+		{
+			name:        "torn last record",
+			records:     []*backuppb.Record{headerRecord(), blogRecord(0), blogRecord(1), checkpointRecord("b", 2), blogRecord(2)},
+			cut:         3,
+			resumeAfter: 3,
+			token:       "b",
+			count:       2,
+		},
+		{
+			name:        "torn checkpoint",
+			records:     []*backuppb.Record{headerRecord(), blogRecord(0), checkpointRecord("a", 1), blogRecord(1), checkpointRecord("b", 2)},
+			cut:         2,
+			resumeAfter: 2,
+			token:       "a",
+			count:       1,
+		},
+		{
+			name:    "complete backup",
+			records: []*backuppb.Record{headerRecord(), blogRecord(0), trailerRecord(t, 1)},
+			wantErr: "already complete",
+		},
+		{
+			name:    "checkpoint with the wrong count",
+			records: []*backuppb.Record{headerRecord(), blogRecord(0), checkpointRecord("a", 2)},
+			wantErr: "counts 2 blogs, 1 were read",
+		},
+		{
+			name:    "no header",
+			records: []*backuppb.Record{blogRecord(0)},
+			wantErr: "does not start with a header",
+		},
+		{
+			name:    "unsupported version",
+			records: []*backuppb.Record{{Header: &backuppb.Header{Version: backupVersion + 1}}},
+			wantErr: "not supported",
+		},
+	}
+	for _, format := range backupFormats {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				path, offsets := writeTestBackup(t, format, tt.records, tt.cut)
+				f, err := os.Open(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+
+				state, err := readBackupState(f)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("readBackupState() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("readBackupState() error = %v", err)
+				}
+				if state.format != format {
+					t.Errorf("format = %q, want %q", state.format, format)
+				}
+				if want := offsets[tt.resumeAfter]; state.offset != want {
+					t.Errorf("offset = %d, want %d", state.offset, want)
+				}
+				if state.token != tt.token {
+					t.Errorf("token = %q, want %q", state.token, tt.token)
+				}
+				// the checksum continues from the checkpoint, so a resumed backup ends with the sum over all blogs
+				want := trailerRecord(t, int(tt.count)).GetTrailer()
+				if state.hasher.count != want.GetCount() || state.hasher.sum() != want.GetSha256() {
+					t.Errorf("hasher = %d blogs %s, want %d blogs %s", state.hasher.count, state.hasher.sum(), want.GetCount(), want.GetSha256())
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyBackup(t *testing.T) {
+	wrongSum := trailerRecord(t, 2)
+	wrongSum.GetTrailer().Sha256 = trailerRecord(t, 1).GetTrailer().GetSha256()
+	wrongCount := trailerRecord(t, 2)
+	wrongCount.GetTrailer().Count = 3
+
+	tests := []struct {
+		name    string
+		records []*backuppb.Record
+		cut     int
+		want    int64
+		wantErr string
+	}{
+		{
+			name:    "complete",
+			records: []*backuppb.Record{headerRecord(), blogRecord(0), checkpointRecord("a", 1), blogRecord(1), trailerRecord(t, 2)},
+			want:    2,
+		},
+		{
+			name:    "no blogs",
+			records: []*backuppb.Record{headerRecord(), trailerRecord(t, 0)},
+			want:    0,
+		},
+		{
+			name:    "no trailer",
+			records: []*backuppb.Record{headerRecord(), blogRecord(0), checkpointRecord("a", 1)},
+			wantErr: "no trailer",
+		},
+		{
+			name:    "torn trailer",
+			records: []*backuppb.Record{headerRecord(), blogRecord(0), trailerRecord(t, 1)},
+			cut:     4,
+			wantErr: "no trailer",
+		},
+		{
+			name:    "checksum mismatch",
+			records: []*backuppb.Record{headerRecord(), blogRecord(0), blogRecord(1), wrongSum},
+			wantErr: "checksum mismatch",
+		},
+		{
+			name:    "trailer with the wrong count",
+			records: []*backuppb.Record{headerRecord(), blogRecord(0), blogRecord(1), wrongCount},
+			wantErr: "trailer counts 3 blogs, 2 were read",
+		},
+		{
+			name:    "checkpoint with the wrong count",
+			records: []*backuppb.Record{headerRecord(), blogRecord(0), checkpointRecord("a", 0), trailerRecord(t, 1)},
+			wantErr: "checkpoint counts 0 blogs, 1 were read",
+		},
+		{
+			name:    "records after the trailer",
+			records: []*backuppb.Record{headerRecord(), blogRecord(0), trailerRecord(t, 1), blogRecord(1)},
+			wantErr: "records follow the trailer",
+		},
+		{
+			name:    "no header",
+			records: []*backuppb.Record{blogRecord(0), trailerRecord(t, 1)},
+			wantErr: "does not start with a header",
+		},
+	}
+	for _, format := range backupFormats {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				path, _ := writeTestBackup(t, format, tt.records, tt.cut)
+				got, err := verifyBackup(path)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("verifyBackup() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("verifyBackup() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("verifyBackup() = %d, want %d", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyBackupNotABackup(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "empty", content: "", wantErr: "the backup is empty"},
+		{name: "text", content: "hello\n", wantErr: "not a backup"},
+		{name: "protobuf without magic", content: "\x05hello", wantErr: "not a backup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "backup")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "blogs")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := verifyBackup(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifyBackup() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
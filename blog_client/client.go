@@ -2,92 +2,117 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"github.com/k-yomo/blog_with_grpc/blogclient"
+	"github.com/k-yomo/blog_with_grpc/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"io"
-	"log"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
 )
 
-func main()  {
-	fmt.Println("Blog Client")
-
-	cc, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
-	if err != nil {
-		log.Fatalf("Could not connect: %v", err)
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: blog_client [flags] <command> [command flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun blog_client <command> -h for the flags of a command.\n\nFlags:\n")
+	flag.PrintDefaults()
+}
 
-	defer cc.Close()
-
-	c := blogpb.NewBlogServiceClient(cc)
-
-	// create blog
-	fmt.Println("Createing the blog")
-	blog := &blogpb.Blog{
-		AuthorId: "k-yomo",
-		Title: "My first blog",
-		Content: "Content of the first blog",
+func main() {
+	server := flag.String("server", envOr("BLOG_SERVER", "localhost:50051"), "address of blog_server")
+	flag.DurationVar(&commandTimeout, "timeout", 10*time.Second, "deadline of each command or REPL line, 0 disables it")
+	serviceConfig := flag.String("service-config", os.Getenv("BLOG_SERVICE_CONFIG"), "file with a gRPC service config replacing the default retry, hedging and timeout policies")
+	output := flag.String("output", "text", "output format: "+strings.Join(outputFormats, ", "))
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
 	}
-	createBlogRes, err := c.CreateBlog(context.Background(), &blogpb.CreateBlogRequest{Blog: blog})
-	if err != nil {
-		log.Fatalf("Unexpected error while creating blog: %v", err)
+	cmd := findCommand(flag.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
 	}
-	fmt.Printf("Blog has been created: %v\n", createBlogRes)
-
-	// read Blog
-	fmt.Println("Reading the blog")
 
-	_, err = c.ReadBlog(context.Background(), &blogpb.ReadBlogRequest{BlogId: "5c54b08ebe020342da2b13a8"})
+	out, err := newPrinter(*output, os.Stdout)
 	if err != nil {
-		fmt.Printf("Error happend while reading: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
 
-	readBlogRes, err := c.ReadBlog(context.Background(), &blogpb.ReadBlogRequest{BlogId: createBlogRes.GetBlog().GetId()})
-	if err != nil {
-		fmt.Printf("Error happend while reading: %v\n", err)
+	if err := run(*server, *serviceConfig, cmd, out, flag.Args()[1:]); err == flag.ErrHelp {
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+}
 
-	fmt.Printf("Blog was read: %v", readBlogRes)
-
-	// update Blog
-	fmt.Println("Createing the blog")
-	updatedBlog := &blogpb.Blog{
-		Id: createBlogRes.GetBlog().GetId(),
-		AuthorId: "abc",
-		Title: "My first blog(updated)",
-		Content: "Content of the first blog(updated)",
-	}
-	updateBlogRes, err := c.UpdateBlog(context.Background(), &blogpb.UpdateBlogRequest{Blog: updatedBlog})
+// run dials the server and runs cmd, closing the connection and flushing traces before returning
+func run(server, serviceConfigPath string, cmd *command, out printer, args []string) error {
+	shutdownTracing, err := tracing.Setup(context.Background(), os.Getenv("BLOG_TRACE_EXPORTER"), "blog_client")
 	if err != nil {
-		log.Fatalf("Unexpected error while creating blog: %v", err)
+		return fmt.Errorf("failed to set up tracing: %v", err)
 	}
-	fmt.Printf("Blog has been updated: %v\n", updateBlogRes)
-
-	// delete Blog
-	deleteRes, err := c.DeleteBlog(context.Background(), &blogpb.DeleteBlogRequest{BlogId: createBlogRes.GetBlog().GetId()})
+	defer shutdownTracing(context.Background())
 
+	// -timeout is applied per command, so the client's own default is disabled
+	opts := []blogclient.Option{
+		blogclient.WithTimeout(0),
+		blogclient.WithDialOptions(grpc.WithStatsHandler(otelgrpc.NewClientHandler())),
+	}
+	if serviceConfigPath != "" {
+		config, err := ioutil.ReadFile(serviceConfigPath)
+		if err != nil {
+			return fmt.Errorf("cannot read service config: %v", err)
+		}
+		opts = append(opts, blogclient.WithServiceConfig(string(config)))
+	}
+	if key := os.Getenv("BLOG_API_KEY"); key != "" {
+		opts = append(opts, blogclient.WithAPIKey(key))
+	} else if token := os.Getenv("BLOG_TOKEN"); token != "" {
+		opts = append(opts, blogclient.WithToken(token))
+	}
+	client, err := blogclient.Dial(server, opts...)
 	if err != nil {
-		fmt.Printf("Error happened while deleting: %v\n", err)
+		return err
 	}
+	defer client.Close()
 
-	fmt.Printf("Blog has been deleted %v\n", deleteRes)
+	ctx := context.Background()
+	if !cmd.interactive {
+		var cancel context.CancelFunc
+		ctx, cancel = withCommandTimeout(ctx)
+		defer cancel()
+	}
+	// results printed before an error are still flushed
+	err = cmd.run(ctx, client, out, args)
+	if flushErr := out.flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
 
-	// list Blogs
-	stream , err := c.ListBlog(context.Background(), &blogpb.ListBlogRequest{})
+// commandTimeout bounds every command, or every line of the REPL
+var commandTimeout time.Duration
 
-	if err != nil {
-		log.Fatalf("Error while calling ListBlog RPC: %v", err)
+func withCommandTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if commandTimeout > 0 {
+		return context.WithTimeout(ctx, commandTimeout)
 	}
+	return context.WithCancel(ctx)
+}
 
-	for {
-		res, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatalf("Something happened: %v", err)
-		}
-		fmt.Println(res.GetBlog())
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
+	return fallback
 }
-
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/k-yomo/blog_with_grpc/blogclient"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// command is a subcommand of blog_client, run gets the arguments after the command name
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, c *blogclient.Client, out printer, args []string) error
+	// interactive and long running commands are not bounded by -timeout as a whole, they bound their own RPCs
+	interactive bool
+}
+
+var commands []*command
+
+func init() {
+	// assigned in init, the repl command refers to commands itself
+	commands = []*command{
+		{name: "create", summary: "Create a blog", run: runCreate},
+		{name: "get", summary: "Print the blogs with the given IDs", run: runGet},
+		{name: "update", summary: "Change fields of a blog", run: runUpdate},
+		{name: "edit", summary: "Edit the content of a blog in $EDITOR", run: runEdit, interactive: true},
+		{name: "delete", summary: "Delete the blogs with the given IDs", run: runDelete},
+		{name: "list", summary: "List all blogs", run: runList},
+		{name: "import", summary: "Create or update blogs from a directory of Markdown files", run: runImport, interactive: true},
+		{name: "export", summary: "Write all blogs as Markdown files or a static HTML site", run: runExport, interactive: true},
+		{name: "backup", summary: "Write all blogs to a JSON Lines or protobuf backup file", run: runBackup, interactive: true},
+		{name: "restore", summary: "Write the blogs of a backup file back under their IDs", run: runRestore, interactive: true},
+		{name: "repl", summary: "Start an interactive shell", run: runRepl, interactive: true},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func newFlagSet(name, args string) *flag.FlagSet {
+	// errors are returned rather than exiting, so a typo does not end the REPL
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: blog_client %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// blogFlags are the flags for the fields of a blog shared by create and update
+type blogFlags struct {
+	fs          *flag.FlagSet
+	authorID    *string
+	title       *string
+	content     *string
+	contentFile *string
+	format      *string
+}
+
+func addBlogFlags(fs *flag.FlagSet) *blogFlags {
+	return &blogFlags{
+		fs:          fs,
+		authorID:    fs.String("author-id", "", "author of the blog, defaults to the caller"),
+		title:       fs.String("title", "", "title of the blog"),
+		content:     fs.String("content", "", "content of the blog"),
+		contentFile: fs.String("content-file", "", "read the content from this file, - for stdin"),
+		format:      fs.String("format", "", "format of the content: plain, markdown or html, the server renders it to HTML"),
+	}
+}
+
+func (f *blogFlags) isSet(name string) bool {
+	set := false
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// apply copies the flags that were given onto blog
+func (f *blogFlags) apply(blog *blogpb.Blog) error {
+	if f.isSet("content") && f.isSet("content-file") {
+		return errors.New("-content and -content-file are mutually exclusive")
+	}
+	if f.isSet("author-id") {
+		blog.AuthorId = *f.authorID
+	}
+	if f.isSet("title") {
+		blog.Title = *f.title
+	}
+	if f.isSet("content") {
+		blog.Content = *f.content
+	}
+	if f.isSet("content-file") {
+		content, err := readContent(*f.contentFile)
+		if err != nil {
+			return err
+		}
+		blog.Content = content
+	}
+	if f.isSet("format") {
+		format, err := parseContentFormat(*f.format)
+		if err != nil {
+			return err
+		}
+		blog.ContentFormat = format
+	}
+	return nil
+}
+
+// parseContentFormat accepts the names of ContentFormat in any case
+func parseContentFormat(name string) (blogpb.ContentFormat, error) {
+	v, ok := blogpb.ContentFormat_value[strings.ToUpper(name)]
+	if !ok || v == int32(blogpb.ContentFormat_CONTENT_FORMAT_UNSPECIFIED) {
+		return 0, fmt.Errorf("unknown content format %q, use plain, markdown or html", name)
+	}
+	return blogpb.ContentFormat(v), nil
+}
+
+// contentFormatName is the lower case name of format, servers treat an unspecified format as plain
+func contentFormatName(format blogpb.ContentFormat) string {
+	if format == blogpb.ContentFormat_CONTENT_FORMAT_UNSPECIFIED {
+		format = blogpb.ContentFormat_PLAIN
+	}
+	return strings.ToLower(format.String())
+}
+
+// readContent reads a whole file, or stdin for "-"
+func readContent(path string) (string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		r = f
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("cannot read content: %v", err)
+	}
+	return string(b), nil
+}
+
+func runCreate(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("create", "")
+	f := addBlogFlags(fs)
+	idempotencyKey := fs.String("idempotency-key", "", "key that makes retries of this create return the same blog")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	blog := &blogpb.Blog{}
+	if err := f.apply(blog); err != nil {
+		return err
+	}
+	created, err := c.CreateBlog(ctx, blog, *idempotencyKey)
+	if err != nil {
+		return err
+	}
+	return out.blog(created)
+}
+
+func runGet(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("get", "<id>...")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("at least one blog ID is required")
+	}
+
+	for _, id := range fs.Args() {
+		blog, err := c.ReadBlog(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := out.blog(blog); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runUpdate(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("update", "<id>")
+	f := addBlogFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("exactly one blog ID is required")
+	}
+
+	// UpdateBlog replaces the blog, so fields without a flag keep their current value
+	blog, err := c.ReadBlog(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := f.apply(blog); err != nil {
+		return err
+	}
+	updated, err := c.UpdateBlog(ctx, blog)
+	if err != nil {
+		return err
+	}
+	return out.blog(updated)
+}
+
+func runEdit(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("edit", "<id>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("exactly one blog ID is required")
+	}
+
+	// the editor can stay open for as long as the user likes, only the RPCs are bounded
+	readCtx, cancel := withCommandTimeout(ctx)
+	blog, err := c.ReadBlog(readCtx, fs.Arg(0))
+	cancel()
+	if err != nil {
+		return err
+	}
+	content, err := editContent(blog.GetContent())
+	if err != nil {
+		return err
+	}
+	if content == blog.GetContent() {
+		fmt.Fprintln(os.Stderr, "No changes")
+		return nil
+	}
+	blog.Content = content
+
+	updateCtx, cancel := withCommandTimeout(ctx)
+	defer cancel()
+	updated, err := c.UpdateBlog(updateCtx, blog)
+	if err != nil {
+		return err
+	}
+	return out.blog(updated)
+}
+
+func runDelete(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("delete", "<id>...")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("at least one blog ID is required")
+	}
+
+	for _, id := range fs.Args() {
+		if err := c.DeleteBlog(ctx, id); err != nil {
+			return err
+		}
+		if err := out.deleted(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listPageSize is the page size of commands that walk every blog
+const listPageSize = 100
+
+// eachBlog calls fn for every blog, paging through ListBlogs so the cap of the ListBlog stream does not apply.
+// Each page is bounded by -timeout.
+func eachBlog(ctx context.Context, c *blogclient.Client, fn func(*blogpb.Blog) error) error {
+	token := ""
+	for {
+		pageCtx, cancel := withCommandTimeout(ctx)
+		blogs, next, err := c.ListBlogs(pageCtx, listPageSize, token)
+		cancel()
+		if err != nil {
+			return err
+		}
+		for _, blog := range blogs {
+			if err := fn(blog); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+func runList(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("list", "")
+	limit := fs.Int("limit", 0, "stop after this many blogs, 0 lists all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	it := c.ListBlog(ctx)
+	defer it.Close()
+	for n := 0; (*limit == 0 || n < *limit) && it.Next(); n++ {
+		if err := out.blog(it.Blog()); err != nil {
+			return err
+		}
+	}
+	if it.Truncated() {
+		fmt.Fprintln(os.Stderr, "Warning: the server truncated the list")
+	}
+	return it.Err()
+}
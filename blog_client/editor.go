@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editorCommand is $VISUAL or $EDITOR, which may carry arguments such as "code --wait"
+func editorCommand() []string {
+	for _, key := range []string{"VISUAL", "EDITOR"} {
+		if args := strings.Fields(os.Getenv(key)); len(args) > 0 {
+			return args
+		}
+	}
+	return []string{"vi"}
+}
+
+// editContent opens content in the editor and returns what was saved
+func editContent(content string) (string, error) {
+	f, err := ioutil.TempFile("", "blog-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	args := editorCommand()
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %v", args[0], err)
+	}
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
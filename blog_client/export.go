@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/k-yomo/blog_with_grpc/blogclient"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var exportFormats = []string{"markdown", "site"}
+
+func runExport(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("export", "<dir>")
+	format := fs.String("format", "markdown", "what to write: "+strings.Join(exportFormats, ", "))
+	title := fs.String("title", "Blog", "title of the static site")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("exactly one directory is required")
+	}
+
+	var w blogWriter
+	switch *format {
+	case "markdown":
+		w = &markdownWriter{dir: fs.Arg(0)}
+	case "site":
+		w = &siteWriter{dir: fs.Arg(0), title: *title}
+	default:
+		return fmt.Errorf("unknown export format %q, use one of %s", *format, strings.Join(exportFormats, ", "))
+	}
+	if err := os.MkdirAll(fs.Arg(0), 0755); err != nil {
+		return err
+	}
+
+	n := 0
+	err := eachBlog(ctx, c, func(blog *blogpb.Blog) error {
+		n++
+		return w.write(blog)
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.finish(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d blogs to %s\n", n, fs.Arg(0))
+	return nil
+}
+
+// blogWriter writes the blogs of an export one by one as they are streamed
+type blogWriter interface {
+	write(*blogpb.Blog) error
+	// finish is called after the last blog
+	finish() error
+}
+
+// markdownWriter writes <id>.md files that import reads back into the same blogs
+type markdownWriter struct {
+	dir string
+}
+
+func (w *markdownWriter) write(blog *blogpb.Blog) error {
+	fm := &frontMatter{ID: blog.GetId(), Title: blog.GetTitle(), Author: blog.GetAuthorId()}
+	if blog.GetContentFormat() != blogpb.ContentFormat_MARKDOWN {
+		fm.Format = contentFormatName(blog.GetContentFormat())
+	}
+	b, err := formatMarkdown(fm, blog.GetContent())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(w.dir, fileName(blog.GetId())+".md"), b, 0644)
+}
+
+func (w *markdownWriter) finish() error { return nil }
+
+// siteWriter writes a page per blog, and index pages of all blogs and of each author once all blogs are written
+type siteWriter struct {
+	dir   string
+	title string
+	// only the fields needed by the indexes are kept
+	entries []siteEntry
+}
+
+type siteEntry struct {
+	ID     string
+	Title  string
+	Author string
+}
+
+func (w *siteWriter) write(blog *blogpb.Blog) error {
+	entry := siteEntry{ID: fileName(blog.GetId()), Title: blog.GetTitle(), Author: blog.GetAuthorId()}
+	w.entries = append(w.entries, entry)
+	return w.render(filepath.Join("posts", entry.ID+".html"), "post", map[string]interface{}{
+		"Site":    w.title,
+		"Root":    "../",
+		"Entry":   entry,
+		"Content": blog.GetContent(),
+		// rendered_html is sanitized by the server
+		"Rendered": template.HTML(blog.GetRenderedHtml()),
+	})
+}
+
+func (w *siteWriter) finish() error {
+	sort.SliceStable(w.entries, func(i, j int) bool {
+		return strings.ToLower(w.entries[i].Title) < strings.ToLower(w.entries[j].Title)
+	})
+	byAuthor := map[string][]siteEntry{}
+	var authors []string
+	for _, e := range w.entries {
+		if _, ok := byAuthor[e.Author]; !ok {
+			authors = append(authors, e.Author)
+		}
+		byAuthor[e.Author] = append(byAuthor[e.Author], e)
+	}
+	sort.Strings(authors)
+
+	for _, author := range authors {
+		err := w.render(filepath.Join("authors", fileName(author)+".html"), "index", map[string]interface{}{
+			"Site":    w.title,
+			"Root":    "../",
+			"Heading": "Blogs by " + author,
+			"Entries": byAuthor[author],
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return w.render("index.html", "index", map[string]interface{}{
+		"Site":    w.title,
+		"Root":    "",
+		"Heading": w.title,
+		"Entries": w.entries,
+		"Authors": authors,
+	})
+}
+
+func (w *siteWriter) render(name, tmpl string, data interface{}) error {
+	path := filepath.Join(w.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := siteTemplates.ExecuteTemplate(f, tmpl, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fileName makes an ID safe to use as a file name, author IDs of old blogs were not validated.
+// Bytes other than lower case letters, digits, - and @ become _ and two hex digits, so distinct IDs
+// never share a file, also on file systems that ignore case.
+func fileName(id string) string {
+	var b strings.Builder
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '@' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// content is shown as the HTML rendered by the server, or as preformatted text by servers that do not render it
+var siteTemplates = template.Must(template.New("").Funcs(template.FuncMap{"fileName": fileName}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { max-width: 46em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; }
+.plain { white-space: pre-wrap; }
+pre { overflow-x: auto; padding: 0.5em; }
+.author { color: #666; }
+</style>
+</head>
+<body>
+{{end}}
+{{define "post"}}{{template "head" .Entry.Title}}<p><a href="{{.Root}}index.html">{{.Site}}</a></p>
+<h1>{{.Entry.Title}}</h1>
+<p class="author">by <a href="{{.Root}}authors/{{fileName .Entry.Author}}.html">{{.Entry.Author}}</a></p>
+{{if .Rendered}}<div class="content">{{.Rendered}}</div>{{else}}<div class="content plain">{{.Content}}</div>{{end}}
+</body>
+</html>
+{{end}}
+{{define "index"}}{{template "head" .Heading}}{{if .Root}}<p><a href="{{.Root}}index.html">{{.Site}}</a></p>
+{{end}}<h1>{{.Heading}}</h1>
+<ul>
+{{range .Entries}}<li><a href="{{$.Root}}posts/{{.ID}}.html">{{.Title}}</a> <span class="author">by {{.Author}}</span></li>
+{{end}}</ul>
+{{if .Authors}}<h2>Authors</h2>
+<ul>
+{{range .Authors}}<li><a href="authors/{{fileName .}}.html">{{.}}</a></li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+{{end}}`))
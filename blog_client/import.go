@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/k-yomo/blog_with_grpc/blogclient"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// authorIDPattern matches the author IDs the server accepts
+var authorIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+
+// importer creates or updates one blog per Markdown file
+type importer struct {
+	client   *blogclient.Client
+	dryRun   bool
+	authorID string
+	// existing blogs by title, for files imported before without an id in their front matter
+	byTitle map[string][]*blogpb.Blog
+}
+
+// importResult is the outcome of one file
+type importResult string
+
+const (
+	importCreated   importResult = "created"
+	importUpdated   importResult = "updated"
+	importUnchanged importResult = "unchanged"
+	importFailed    importResult = "failed"
+)
+
+func runImport(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("import", "<dir>")
+	dryRun := fs.Bool("dry-run", false, "report what would change without writing")
+	authorID := fs.String("author-id", "", "author of files without an author in their front matter, defaults to the caller")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("exactly one directory is required")
+	}
+
+	imp := &importer{client: c, dryRun: *dryRun, authorID: *authorID, byTitle: map[string][]*blogpb.Blog{}}
+	if err := imp.loadExisting(ctx); err != nil {
+		return fmt.Errorf("cannot list existing blogs: %v", err)
+	}
+
+	counts := map[importResult]int{}
+	root := fs.Arg(0)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !isMarkdown(path) {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		result, id, err := imp.importFile(ctx, path, rel)
+		counts[result]++
+		if err := out.imported(string(result), rel, id, err); err != nil {
+			return err
+		}
+		// an expired deadline fails every file that follows, so stop
+		return ctx.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	prefix := ""
+	if imp.dryRun {
+		prefix = "dry run: "
+	}
+	fmt.Fprintf(os.Stderr, "%s%d created, %d updated, %d unchanged, %d failed\n", prefix,
+		counts[importCreated], counts[importUpdated], counts[importUnchanged], counts[importFailed])
+	if counts[importFailed] > 0 {
+		return fmt.Errorf("%d files failed to import", counts[importFailed])
+	}
+	return nil
+}
+
+func isMarkdown(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// loadExisting pages through all blogs, a blog missing here would be created again by a file without an id
+func (imp *importer) loadExisting(ctx context.Context) error {
+	return eachBlog(ctx, imp.client, func(blog *blogpb.Blog) error {
+		imp.byTitle[blog.GetTitle()] = append(imp.byTitle[blog.GetTitle()], blog)
+		return nil
+	})
+}
+
+// importFile maps a file to a blog and writes it, rel is the path used in the report
+func (imp *importer) importFile(ctx context.Context, path, rel string) (importResult, string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return importFailed, "", err
+	}
+	fm, body, err := parseMarkdown(b)
+	if err != nil {
+		return importFailed, "", err
+	}
+
+	slug := fm.Slug
+	if slug == "" {
+		slug = strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel))
+	}
+	format := blogpb.ContentFormat_MARKDOWN
+	if fm.Format != "" {
+		if format, err = parseContentFormat(fm.Format); err != nil {
+			return importFailed, "", err
+		}
+	}
+	author := fm.Author
+	if author != "" && !authorIDPattern.MatchString(author) {
+		// static site generators put display names such as "Jane Doe" here, which are no author IDs
+		fmt.Fprintf(os.Stderr, "Warning: %s: author %q is not an author ID, using -author-id instead\n", rel, author)
+		author = ""
+	}
+	blog := &blogpb.Blog{Id: fm.ID, AuthorId: author, Title: fm.Title, Content: body, ContentFormat: format}
+	if blog.Title == "" {
+		blog.Title = slug
+	}
+	if blog.AuthorId == "" {
+		blog.AuthorId = imp.authorID
+	}
+
+	current, err := imp.find(ctx, blog)
+	if err != nil {
+		return importFailed, "", err
+	}
+	if current == nil {
+		if imp.dryRun {
+			return importCreated, "", nil
+		}
+		ctx, cancel := withCommandTimeout(ctx)
+		defer cancel()
+		created, err := imp.client.CreateBlog(ctx, blog, importKey(slug, blog))
+		if err != nil {
+			return importFailed, "", err
+		}
+		return importCreated, created.GetId(), nil
+	}
+
+	blog.Id = current.GetId()
+	if blog.AuthorId == "" {
+		blog.AuthorId = current.GetAuthorId()
+	}
+	if blog.GetAuthorId() == current.GetAuthorId() && blog.GetTitle() == current.GetTitle() && blog.GetContent() == current.GetContent() &&
+		blog.GetContentFormat() == current.GetContentFormat() {
+		return importUnchanged, blog.Id, nil
+	}
+	if imp.dryRun {
+		return importUpdated, blog.Id, nil
+	}
+	ctx, cancel := withCommandTimeout(ctx)
+	defer cancel()
+	if _, err := imp.client.UpdateBlog(ctx, blog); err != nil {
+		return importFailed, blog.Id, err
+	}
+	return importUpdated, blog.Id, nil
+}
+
+// find returns the blog a file was imported into before: the one with the id of the front matter,
+// or else the only one with the same title and author. It returns nil for new files.
+func (imp *importer) find(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	if blog.GetId() != "" {
+		ctx, cancel := withCommandTimeout(ctx)
+		defer cancel()
+		current, err := imp.client.ReadBlog(ctx, blog.GetId())
+		if blogclient.IsNotFound(err) {
+			return nil, fmt.Errorf("blog %s of the front matter does not exist", blog.GetId())
+		}
+		return current, err
+	}
+
+	var matches []*blogpb.Blog
+	for _, b := range imp.byTitle[blog.GetTitle()] {
+		if blog.GetAuthorId() == "" || b.GetAuthorId() == blog.GetAuthorId() {
+			matches = append(matches, b)
+		}
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("%d blogs are titled %q, set the id in the front matter", len(matches), blog.GetTitle())
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return nil, nil
+}
+
+// importKey makes a retried import of an unchanged file return the blog created first
+func importKey(slug string, blog *blogpb.Blog) string {
+	h := sha256.New()
+	for _, v := range []string{slug, blog.GetAuthorId(), blog.GetTitle(), blog.GetContent(), blog.GetContentFormat().String()} {
+		fmt.Fprintf(h, "%d:%s", len(v), v)
+	}
+	return "import:" + hex.EncodeToString(h.Sum(nil))
+}
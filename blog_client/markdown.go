@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+	"strings"
+)
+
+// frontMatter is the metadata block at the top of a Markdown file, as written by static site generators.
+// Blog has no fields for tags or dates, keys other than these are ignored.
+type frontMatter struct {
+	ID     string `yaml:"id,omitempty" toml:"id,omitempty"`
+	Title  string `yaml:"title" toml:"title"`
+	Author string `yaml:"author,omitempty" toml:"author,omitempty"`
+	Slug   string `yaml:"slug,omitempty" toml:"slug,omitempty"`
+	// Format is the content format, files are Markdown unless it says otherwise
+	Format string `yaml:"format,omitempty" toml:"format,omitempty"`
+}
+
+// parseMarkdown splits a file into its front matter and body.
+// YAML front matter is fenced by "---" lines and TOML front matter by "+++" lines, a file without either is all body.
+func parseMarkdown(b []byte) (*frontMatter, string, error) {
+	text := strings.Replace(string(b), "\r\n", "\n", -1)
+	fm := &frontMatter{}
+	var fence string
+	switch {
+	case strings.HasPrefix(text, "---\n"):
+		fence = "---"
+	case strings.HasPrefix(text, "+++\n"):
+		fence = "+++"
+	default:
+		return fm, text, nil
+	}
+
+	rest := text[len(fence)+1:]
+	end := strings.Index(rest, "\n"+fence+"\n")
+	var meta, body string
+	switch {
+	case strings.HasPrefix(rest, fence+"\n"):
+		meta, body = "", rest[len(fence)+1:]
+	case end >= 0:
+		meta, body = rest[:end], rest[end+len(fence)+2:]
+	case strings.HasSuffix(rest, "\n"+fence):
+		meta, body = rest[:len(rest)-len(fence)-1], ""
+	default:
+		return nil, "", errors.New("front matter is not closed")
+	}
+
+	var err error
+	if fence == "---" {
+		err = yaml.Unmarshal([]byte(meta), fm)
+	} else {
+		err = toml.Unmarshal([]byte(meta), fm)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid front matter: %v", err)
+	}
+	// the blank line after the fence belongs to the layout, not the content
+	return fm, strings.TrimPrefix(body, "\n"), nil
+}
+
+// formatMarkdown writes fm as YAML front matter followed by body, the inverse of parseMarkdown
+func formatMarkdown(fm *frontMatter, body string) ([]byte, error) {
+	meta, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("---\n%s---\n\n%s", meta, body)), nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		wantMeta frontMatter
+		wantBody string
+		wantErr  string
+	}{
+		{
+			name:     "YAML front matter",
+			file:     "---\nid: 5c8f\ntitle: \"Hello: world\"\nauthor: alice\nslug: hello\nformat: html\n---\n\n<p>hi</p>\n",
+			wantMeta: frontMatter{ID: "5c8f", Title: "Hello: world", Author: "alice", Slug: "hello", Format: "html"},
+			wantBody: "<p>hi</p>\n",
+		},
+		{
+			name:     "TOML front matter",
+			file:     "+++\nid = \"5c8f\"\ntitle = \"Hello\"\nauthor = \"alice\"\n+++\n\n# Hello\n",
+			wantMeta: frontMatter{ID: "5c8f", Title: "Hello", Author: "alice"},
+			wantBody: "# Hello\n",
+		},
+		{
+			name:     "keys of other generators are ignored",
+			file:     "---\ntitle: Hello\ntags: [go, grpc]\ndate: 2019-01-02\ndraft: true\n---\nbody\n",
+			wantMeta: frontMatter{Title: "Hello"},
+			wantBody: "body\n",
+		},
+		{
+			name:     "TOML tables are ignored",
+			file:     "+++\ntitle = \"Hello\"\n[params]\ncolor = \"red\"\n+++\nbody\n",
+			wantMeta: frontMatter{Title: "Hello"},
+			wantBody: "body\n",
+		},
+		{
+			name:     "Windows line endings",
+			file:     "---\r\ntitle: Hello\r\n---\r\n\r\nline 1\r\nline 2\r\n",
+			wantMeta: frontMatter{Title: "Hello"},
+			wantBody: "line 1\nline 2\n",
+		},
+		{
+			name:     "no front matter",
+			file:     "# Hello\n\n---\n\ntitle: not metadata\n",
+			wantBody: "# Hello\n\n---\n\ntitle: not metadata\n",
+		},
+		{
+			name:     "a fence inside a line does not start front matter",
+			file:     "--- hello\n",
+			wantBody: "--- hello\n",
+		},
+		{
+			name:     "empty front matter",
+			file:     "+++\n+++\nbody",
+			wantBody: "body",
+		},
+		{
+			name:     "front matter without a body",
+			file:     "---\ntitle: Hello\n---",
+			wantMeta: frontMatter{Title: "Hello"},
+		},
+		{
+			name:     "the first closing fence ends the front matter",
+			file:     "---\ntitle: Hello\n---\nabove\n---\nbelow\n",
+			wantMeta: frontMatter{Title: "Hello"},
+			wantBody: "above\n---\nbelow\n",
+		},
+		{
+			name:    "unclosed front matter",
+			file:    "---\ntitle: Hello\n\nbody\n",
+			wantErr: "front matter is not closed",
+		},
+		{
+			name:    "closed by the other fence",
+			file:    "+++\ntitle = \"Hello\"\n---\nbody\n",
+			wantErr: "front matter is not closed",
+		},
+		{
+			name:    "invalid YAML",
+			file:    "---\ntitle: [unterminated\n---\nbody\n",
+			wantErr: "invalid front matter",
+		},
+		{
+			name:    "invalid TOML",
+			file:    "+++\ntitle = Hello\n+++\nbody\n",
+			wantErr: "invalid front matter",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, body, err := parseMarkdown([]byte(tt.file))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseMarkdown() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMarkdown() error = %v", err)
+			}
+			if *fm != tt.wantMeta {
+				t.Errorf("front matter = %+v, want %+v", *fm, tt.wantMeta)
+			}
+			if body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestFormatMarkdownRoundTrip(t *testing.T) {
+	want := &frontMatter{ID: "5c8f", Title: "a: b # c", Author: "alice", Format: "plain"}
+	const body = "---\nnot front matter\n+++\n"
+	b, err := formatMarkdown(want, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, gotBody, err := parseMarkdown(b)
+	if err != nil {
+		t.Fatalf("parseMarkdown() error = %v\n%s", err, b)
+	}
+	if !reflect.DeepEqual(got, want) || gotBody != body {
+		t.Errorf("parseMarkdown(formatMarkdown()) = %+v %q, want %+v %q", got, gotBody, want, body)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v2"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer writes command results in one output format
+type printer interface {
+	blog(*blogpb.Blog) error
+	deleted(blogID string) error
+	// imported reports the result of importing one file, failure is set for failed files
+	imported(result, path, blogID string, failure error) error
+	// flush is called once after the last result
+	flush() error
+}
+
+var outputFormats = []string{"text", "json", "yaml", "table"}
+
+func newPrinter(format string, w io.Writer) (printer, error) {
+	switch format {
+	case "text":
+		return &textPrinter{w: w}, nil
+	case "json":
+		return &jsonPrinter{w: w}, nil
+	case "yaml":
+		return &yamlPrinter{w: w}, nil
+	case "table":
+		return newTablePrinter(w), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, use one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// marshalJSON encodes m with the proto field names and zero values, as the REST gateway does
+func marshalJSON(m proto.Message) ([]byte, error) {
+	return protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(proto.MessageV2(m))
+}
+
+type textPrinter struct {
+	w io.Writer
+}
+
+func (p *textPrinter) blog(blog *blogpb.Blog) error {
+	_, err := fmt.Fprintf(p.w, "id:        %s\nauthor_id: %s\ntitle:     %s\nformat:    %s\n\n%s\n\n",
+		blog.GetId(), blog.GetAuthorId(), blog.GetTitle(), contentFormatName(blog.GetContentFormat()), blog.GetContent())
+	return err
+}
+
+func (p *textPrinter) deleted(blogID string) error {
+	_, err := fmt.Fprintf(p.w, "Deleted %s\n", blogID)
+	return err
+}
+
+func (p *textPrinter) imported(result, path, blogID string, failure error) error {
+	if failure != nil {
+		_, err := fmt.Fprintf(p.w, "%-9s %s: %v\n", result, path, failure)
+		return err
+	}
+	_, err := fmt.Fprintf(p.w, "%-9s %s %s\n", result, path, blogID)
+	return err
+}
+
+func (p *textPrinter) flush() error { return nil }
+
+// importRecord is how the structured printers write the result of importing a file
+type importRecord struct {
+	Result string `json:"result" yaml:"result"`
+	Path   string `json:"path" yaml:"path"`
+	BlogID string `json:"blog_id,omitempty" yaml:"blog_id,omitempty"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func newImportRecord(result, path, blogID string, err error) *importRecord {
+	r := &importRecord{Result: result, Path: path, BlogID: blogID}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// jsonPrinter writes one JSON object per line, so lists can be streamed into jq
+type jsonPrinter struct {
+	w io.Writer
+}
+
+func (p *jsonPrinter) write(m proto.Message) error {
+	b, err := marshalJSON(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", b)
+	return err
+}
+
+func (p *jsonPrinter) blog(blog *blogpb.Blog) error {
+	return p.write(blog)
+}
+
+func (p *jsonPrinter) deleted(blogID string) error {
+	return p.write(&blogpb.DeleteBlogResponse{BlogId: blogID})
+}
+
+func (p *jsonPrinter) imported(result, path, blogID string, failure error) error {
+	b, err := json.Marshal(newImportRecord(result, path, blogID, failure))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", b)
+	return err
+}
+
+func (p *jsonPrinter) flush() error { return nil }
+
+// yamlPrinter writes one YAML document per result
+type yamlPrinter struct {
+	w io.Writer
+}
+
+func (p *yamlPrinter) write(m proto.Message) error {
+	// going through JSON keeps the proto field names
+	b, err := marshalJSON(m)
+	if err != nil {
+		return err
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "---\n%s", out)
+	return err
+}
+
+func (p *yamlPrinter) blog(blog *blogpb.Blog) error {
+	return p.write(blog)
+}
+
+func (p *yamlPrinter) deleted(blogID string) error {
+	return p.write(&blogpb.DeleteBlogResponse{BlogId: blogID})
+}
+
+func (p *yamlPrinter) imported(result, path, blogID string, failure error) error {
+	out, err := yaml.Marshal(newImportRecord(result, path, blogID, failure))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "---\n%s", out)
+	return err
+}
+
+func (p *yamlPrinter) flush() error { return nil }
+
+// tablePrinter aligns blogs in columns, content is cut to its first line
+type tablePrinter struct {
+	tw     *tabwriter.Writer
+	header bool
+}
+
+const maxCellLength = 40
+
+func newTablePrinter(w io.Writer) *tablePrinter {
+	return &tablePrinter{tw: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)}
+}
+
+func cell(s string) string {
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		s = s[:i] + "…"
+	}
+	if r := []rune(s); len(r) > maxCellLength {
+		s = string(r[:maxCellLength-1]) + "…"
+	}
+	return strings.Replace(s, "\t", " ", -1)
+}
+
+func (p *tablePrinter) blog(blog *blogpb.Blog) error {
+	if !p.header {
+		fmt.Fprintln(p.tw, "ID\tAUTHOR\tTITLE\tCONTENT")
+		p.header = true
+	}
+	_, err := fmt.Fprintf(p.tw, "%s\t%s\t%s\t%s\n", blog.GetId(), cell(blog.GetAuthorId()), cell(blog.GetTitle()), cell(blog.GetContent()))
+	return err
+}
+
+func (p *tablePrinter) deleted(blogID string) error {
+	if !p.header {
+		fmt.Fprintln(p.tw, "DELETED")
+		p.header = true
+	}
+	_, err := fmt.Fprintln(p.tw, blogID)
+	return err
+}
+
+func (p *tablePrinter) imported(result, path, blogID string, failure error) error {
+	if !p.header {
+		fmt.Fprintln(p.tw, "RESULT\tPATH\tID\tERROR")
+		p.header = true
+	}
+	r := newImportRecord(result, path, blogID, failure)
+	_, err := fmt.Fprintf(p.tw, "%s\t%s\t%s\t%s\n", r.Result, cell(r.Path), r.BlogID, cell(r.Error))
+	return err
+}
+
+func (p *tablePrinter) flush() error {
+	// the REPL flushes after every line, each of which starts a new table
+	p.header = false
+	return p.tw.Flush()
+}
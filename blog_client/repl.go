@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/k-yomo/blog_with_grpc/blogclient"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"github.com/peterh/liner"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const replPrompt = "blog> "
+
+var replBuiltins = []string{"help", "exit", "quit"}
+
+// repl runs commands read from the terminal against one connection
+type repl struct {
+	client *blogclient.Client
+	// ids are the blog IDs offered by tab completion
+	ids map[string]bool
+}
+
+// idRecorder keeps the completion cache in step with the blogs the commands print
+type idRecorder struct {
+	printer
+	ids map[string]bool
+}
+
+func (r *idRecorder) blog(blog *blogpb.Blog) error {
+	r.ids[blog.GetId()] = true
+	return r.printer.blog(blog)
+}
+
+func (r *idRecorder) deleted(blogID string) error {
+	delete(r.ids, blogID)
+	return r.printer.deleted(blogID)
+}
+
+func runRepl(ctx context.Context, c *blogclient.Client, out printer, args []string) error {
+	fs := newFlagSet("repl", "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r := &repl{client: c, ids: map[string]bool{}}
+	out = &idRecorder{printer: out, ids: r.ids}
+	if err := r.loadIDs(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: blog IDs will not be completed: %v\n", err)
+	}
+
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetWordCompleter(r.complete)
+
+	historyPath := replHistoryPath()
+	if f, err := os.Open(historyPath); err == nil {
+		line.ReadHistory(f)
+		f.Close()
+	}
+	defer func() {
+		if historyPath == "" {
+			return
+		}
+		if f, err := os.Create(historyPath); err == nil {
+			line.WriteHistory(f)
+			f.Close()
+		}
+	}()
+
+	for {
+		input, err := line.Prompt(replPrompt)
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		words, err := splitLine(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		line.AppendHistory(input)
+
+		switch words[0] {
+		case "exit", "quit":
+			return nil
+		case "help":
+			replHelp()
+			continue
+		}
+		if err := r.exec(ctx, out, words); err != nil && err != flag.ErrHelp {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+}
+
+// exec runs one line, each line gets its own -timeout
+func (r *repl) exec(ctx context.Context, out printer, words []string) error {
+	cmd := findCommand(words[0])
+	if cmd == nil || cmd.name == "repl" {
+		return fmt.Errorf("unknown command %q, type help for the list of commands", words[0])
+	}
+	if !cmd.interactive {
+		var cancel context.CancelFunc
+		ctx, cancel = withCommandTimeout(ctx)
+		defer cancel()
+	}
+	err := cmd.run(ctx, r.client, out, words[1:])
+	if flushErr := out.flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// loadIDs fills the completion cache with the IDs of the existing blogs
+func (r *repl) loadIDs(ctx context.Context) error {
+	ctx, cancel := withCommandTimeout(ctx)
+	defer cancel()
+	it := r.client.ListBlog(ctx)
+	defer it.Close()
+	for it.Next() {
+		r.ids[it.Blog().GetId()] = true
+	}
+	return it.Err()
+}
+
+// complete completes command names in the first word and blog IDs after it
+func (r *repl) complete(line string, pos int) (head string, completions []string, tail string) {
+	head, tail = line[:pos], line[pos:]
+	start := strings.LastIndexAny(head, " \t") + 1
+	head, word := head[:start], head[start:]
+
+	var candidates []string
+	if strings.TrimSpace(head) == "" {
+		candidates = append(candidates, replBuiltins...)
+		for _, cmd := range commands {
+			if cmd.name != "repl" {
+				candidates = append(candidates, cmd.name)
+			}
+		}
+	} else if !strings.HasPrefix(word, "-") {
+		for id := range r.ids {
+			candidates = append(candidates, id)
+		}
+	}
+
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			completions = append(completions, c+" ")
+		}
+	}
+	sort.Strings(completions)
+	return head, completions, tail
+}
+
+func replHelp() {
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, cmd := range commands {
+		if cmd.name != "repl" {
+			fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "  %-8s %s\n\nRun <command> -h for the flags of a command.\n", "exit", "Leave the shell")
+}
+
+// replHistoryPath is where the history is kept between sessions, empty if there is no home directory
+func replHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".blog_client_history")
+}
+
+// splitLine splits a line into words like a shell, honouring quotes and backslashes
+func splitLine(line string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("line ends with a backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
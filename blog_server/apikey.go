@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/k-yomo/blog_with_grpc/apikeypb"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+	"time"
+)
+
+type apiKeyServer struct {
+	keys *mongo.Collection
+}
+
+type apiKeyItem struct {
+	ID        primitive.ObjectID `bson:"_id, omitempty"`
+	Name      string             `bson:"name"`
+	OwnerID   string             `bson:"owner_id"`
+	Role      role               `bson:"role"`
+	Scopes    []scope            `bson:"scopes"`
+	Hash      string             `bson:"hash"`
+	CreatedAt time.Time          `bson:"created_at"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty"`
+}
+
+func (*apiKeyServer) requireLogin(ctx context.Context) (*caller, error) {
+	c := callerFromContext(ctx)
+	if c == nil {
+		return nil, unauthenticatedError(reasonAuthenticationRequired, "Authentication required")
+	}
+	// keys are managed by humans, a key cannot be used to mint or revoke keys
+	if c.APIKeyID != "" {
+		return nil, permissionDeniedError(reasonAPIKeyNotAllowed, nil, "API keys cannot manage API keys")
+	}
+	return c, nil
+}
+
+func (s *apiKeyServer) CreateKey(ctx context.Context, req *apikeypb.CreateKeyRequest) (*apikeypb.CreateKeyResponse, error) {
+	c, err := s.requireLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetName() == "" {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "name", Description: "is required"}})
+	}
+	if len(req.GetScopes()) == 0 {
+		return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "scopes", Description: "must hold at least one scope"}})
+	}
+
+	item := &apiKeyItem{
+		ID:        primitive.NewObjectID(),
+		Name:      req.GetName(),
+		OwnerID:   c.ID,
+		Role:      roleAuthor,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, sc := range req.GetScopes() {
+		if sc == apikeypb.Scope_SCOPE_UNSPECIFIED {
+			return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: "scopes", Description: "must not hold " + sc.String()}})
+		}
+		v := scope(strings.ToLower(sc.String()))
+		if v == scopeAdmin {
+			if !c.isAdmin() {
+				return nil, permissionDeniedError(reasonAdminRequired, nil, "Only admins can create keys with the admin scope")
+			}
+			item.Role = roleAdmin
+		}
+		item.Scopes = append(item.Scopes, v)
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, internalError(ctx, reasonInternal, err)
+	}
+	// the key ID is part of the secret so a key can be looked up without scanning hashes
+	secret := item.ID.Hex() + "." + base64.RawURLEncoding.EncodeToString(random)
+	item.Hash = hashSecret(secret)
+
+	opCtx, done := startOp(ctx, apiKeyCollection, "insert")
+	_, err = s.keys.InsertOne(opCtx, item)
+	done(&err)
+	if err != nil {
+		return nil, storageError(ctx, err, resourceAPIKey, item.ID.Hex())
+	}
+
+	return &apikeypb.CreateKeyResponse{Key: item.toApiKeyPb(), Secret: secret}, nil
+}
+
+func (s *apiKeyServer) ListKeys(ctx context.Context, req *apikeypb.ListKeysRequest) (*apikeypb.ListKeysResponse, error) {
+	c, err := s.requireLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opCtx, done := startOp(ctx, apiKeyCollection, "find")
+	items, err := s.findByOwner(opCtx, c.ID)
+	done(&err)
+	if err != nil {
+		return nil, storageError(ctx, err, resourceAPIKey, "")
+	}
+
+	res := &apikeypb.ListKeysResponse{}
+	for _, item := range items {
+		res.Keys = append(res.Keys, item.toApiKeyPb())
+	}
+	return res, nil
+}
+
+func (s *apiKeyServer) findByOwner(ctx context.Context, ownerID string) ([]*apiKeyItem, error) {
+	cursor, err := s.keys.Find(ctx, bson.M{"owner_id": ownerID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []*apiKeyItem
+	for cursor.Next(ctx) {
+		item := &apiKeyItem{}
+		if err := cursor.Decode(item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, cursor.Err()
+}
+
+func (s *apiKeyServer) RevokeKey(ctx context.Context, req *apikeypb.RevokeKeyRequest) (*apikeypb.RevokeKeyResponse, error) {
+	c, err := s.requireLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := primitive.ObjectIDFromHex(req.GetKeyId())
+	if err != nil {
+		return nil, invalidIDError("key_id", req.GetKeyId())
+	}
+
+	item := &apiKeyItem{}
+	filter := bson.M{"_id": oid}
+	opCtx, done := startOp(ctx, apiKeyCollection, "find_one")
+	err = s.keys.FindOne(opCtx, filter).Decode(item)
+	done(&err)
+	if err != nil {
+		return nil, storageError(ctx, err, resourceAPIKey, req.GetKeyId())
+	}
+	if item.OwnerID != c.ID && !c.isAdmin() {
+		return nil, permissionDeniedError(reasonNotOwner, nil, "Cannot revoke keys of other users")
+	}
+
+	if item.RevokedAt == nil {
+		now := time.Now().UTC()
+		item.RevokedAt = &now
+		opCtx, done := startOp(ctx, apiKeyCollection, "replace")
+		_, err = s.keys.ReplaceOne(opCtx, filter, item)
+		done(&err)
+		if err != nil {
+			return nil, storageError(ctx, err, resourceAPIKey, req.GetKeyId())
+		}
+	}
+	return &apikeypb.RevokeKeyResponse{Key: item.toApiKeyPb()}, nil
+}
+
+// lookup resolves a presented secret into an active key
+func (s *apiKeyServer) lookup(ctx context.Context, secret string) (*apiKeyItem, error) {
+	i := strings.IndexByte(secret, '.')
+	if i < 0 {
+		return nil, unauthenticatedError(reasonInvalidAPIKey, "Malformed API key")
+	}
+	oid, err := primitive.ObjectIDFromHex(secret[:i])
+	if err != nil {
+		return nil, unauthenticatedError(reasonInvalidAPIKey, "Malformed API key")
+	}
+
+	item := &apiKeyItem{}
+	opCtx, done := startOp(ctx, apiKeyCollection, "find_one")
+	err = s.keys.FindOne(opCtx, bson.M{"_id": oid}).Decode(item)
+	done(&err)
+	if err == mongo.ErrNoDocuments {
+		return nil, unauthenticatedError(reasonInvalidAPIKey, "Invalid API key")
+	} else if err != nil {
+		return nil, storageError(ctx, err, resourceAPIKey, oid.Hex())
+	}
+	if subtle.ConstantTimeCompare([]byte(item.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, unauthenticatedError(reasonInvalidAPIKey, "Invalid API key")
+	}
+	if item.RevokedAt != nil {
+		return nil, unauthenticatedError(reasonAPIKeyRevoked, "API key has been revoked")
+	}
+	return item, nil
+}
+
+// hashSecret returns the digest stored in place of the secret.
+// Secrets are 256 bit random values, so a plain SHA-256 is enough to make a leaked collection useless.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k *apiKeyItem) toApiKeyPb() *apikeypb.ApiKey {
+	key := &apikeypb.ApiKey{
+		Id:      k.ID.Hex(),
+		Name:    k.Name,
+		OwnerId: k.OwnerID,
+	}
+	for _, sc := range k.Scopes {
+		key.Scopes = append(key.Scopes, apikeypb.Scope(apikeypb.Scope_value[strings.ToUpper(string(sc))]))
+	}
+	key.CreateTime, _ = ptypes.TimestampProto(k.CreatedAt)
+	if k.RevokedAt != nil {
+		key.RevokeTime, _ = ptypes.TimestampProto(*k.RevokedAt)
+	}
+	return key
+}
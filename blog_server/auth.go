@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type role string
+
+const (
+	roleAuthor role = "author"
+	roleAdmin  role = "admin"
+)
+
+type scope string
+
+const (
+	scopeRead  scope = "read"
+	scopeWrite scope = "write"
+	scopeAdmin scope = "admin"
+)
+
+// caller is the authenticated identity behind a request
+type caller struct {
+	ID     string
+	Role   role
+	Scopes []scope
+	// APIKeyID is set when the caller authenticated with an API key instead of a login token
+	APIKeyID string
+}
+
+func (c *caller) isAdmin() bool {
+	return c.Role == roleAdmin && c.hasScope(scopeAdmin)
+}
+
+func (c *caller) hasScope(sc scope) bool {
+	for _, s := range c.Scopes {
+		if s == sc {
+			return true
+		}
+	}
+	return false
+}
+
+// scopesOf returns the scopes a login token of the role grants
+func scopesOf(r role) []scope {
+	if r == roleAdmin {
+		return []scope{scopeRead, scopeWrite, scopeAdmin}
+	}
+	return []scope{scopeRead, scopeWrite}
+}
+
+// apiKeyHeader is the metadata key machine clients send their API key in
+const apiKeyHeader = "x-api-key"
+
+type callerKey struct{}
+
+func withCaller(ctx context.Context, c *caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+// callerFromContext returns the caller set by the auth interceptor, nil for anonymous requests
+func callerFromContext(ctx context.Context) *caller {
+	c, _ := ctx.Value(callerKey{}).(*caller)
+	return c
+}
+
+type tokenClaims struct {
+	Role role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type authenticator struct {
+	secret []byte
+	keys   *apiKeyServer
+}
+
+// authenticate resolves the API key or bearer token in the request metadata into a caller.
+// Requests without credentials are let through anonymously, handlers decide what they may do.
+func (a *authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	if key := metautils.ExtractIncoming(ctx).Get(apiKeyHeader); key != "" {
+		item, err := a.keys.lookup(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		grpc_ctxtags.Extract(ctx).Set(callerTag, item.OwnerID).Set("api_key_id", item.ID.Hex())
+		return withCaller(ctx, &caller{ID: item.OwnerID, Role: item.Role, Scopes: item.Scopes, APIKeyID: item.ID.Hex()}), nil
+	}
+
+	token, err := grpc_auth.AuthFromMD(ctx, "bearer")
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			return ctx, nil
+		}
+		return nil, err
+	}
+
+	claims := &tokenClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return a.secret, nil
+	})
+	if err != nil {
+		return nil, unauthenticatedError(reasonInvalidToken, fmt.Sprintf("Invalid token: %v", err))
+	}
+	if claims.Subject == "" {
+		return nil, unauthenticatedError(reasonInvalidToken, "Token has no subject")
+	}
+	if claims.Role == "" {
+		claims.Role = roleAuthor
+	}
+
+	grpc_ctxtags.Extract(ctx).Set(callerTag, claims.Subject)
+	return withCaller(ctx, &caller{ID: claims.Subject, Role: claims.Role, Scopes: scopesOf(claims.Role)}), nil
+}
+
+// authorizeRead checks that the caller may read blogs, anonymous callers may, but credentials must carry the read scope
+func authorizeRead(ctx context.Context) error {
+	if c := callerFromContext(ctx); c != nil && !c.hasScope(scopeRead) {
+		return missingScopeError(scopeRead)
+	}
+	return nil
+}
+
+// authorizeAuthor checks that the caller may write blogs owned by authorID
+func authorizeAuthor(ctx context.Context, authorID string) error {
+	c := callerFromContext(ctx)
+	if c == nil {
+		return unauthenticatedError(reasonAuthenticationRequired, "Authentication required")
+	}
+	if !c.hasScope(scopeWrite) {
+		return missingScopeError(scopeWrite)
+	}
+	if c.isAdmin() || c.ID == authorID {
+		return nil
+	}
+	return permissionDeniedError(reasonNotOwner, map[string]string{"author_id": authorID},
+		fmt.Sprintf("%s is not allowed to modify blogs of %s", c.ID, authorID))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// contextError reports a call whose context ended as Canceled or DeadlineExceeded,
+// instead of whatever error the aborted storage call surfaced as
+func contextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded:
+		return err
+	}
+	return status.FromContextError(ctx.Err()).Err()
+}
+
+// withDefaultTimeout bounds ctx by timeout unless the client already sent a deadline or timeout is 0
+func withDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// deadlineUnaryInterceptor applies the default deadline to unary calls
+func deadlineUnaryInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := withDefaultTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// withMaxTimeout bounds ctx by timeout even when the client sent a later deadline, unless timeout is 0
+func withMaxTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// deadlineStreamInterceptor caps the duration of server streams, so a stalled client cannot hold a cursor open
+func deadlineStreamInterceptor(timeout time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := withMaxTimeout(stream.Context(), timeout)
+		defer cancel()
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// contextErrorUnaryInterceptor applies contextError, it runs inside the metrics and logging interceptors
+// so a timeout is recorded as DEADLINE_EXCEEDED rather than as the storage error it caused
+func contextErrorUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, contextError(ctx, err)
+}
+
+func contextErrorStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return contextError(stream.Context(), handler(srv, stream))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"github.com/swaggo/files/v2"
+	"net/http"
+)
+
+// explorerPage renders /openapi.yaml with Swagger UI, try-it-out calls go to the gateway it is served from.
+// Its assets are embedded in the binary, so the page works offline and loads no third party scripts.
+const explorerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Blog API</title>
+  <link rel="stylesheet" href="/docs/assets/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/assets/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.yaml", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// withDocs serves the OpenAPI document and its explorer next to the REST routes of gateway
+func withDocs(gateway http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", gateway)
+	mux.HandleFunc("/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(blogpb.OpenAPISpec)
+	})
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(explorerPage))
+	})
+	mux.Handle("/docs/assets/", http.StripPrefix("/docs/assets/", http.FileServer(http.FS(swaggerFiles.FS))))
+	return mux
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus/ctxlogrus"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// errorDomain is the ErrorInfo domain of errors raised by this service
+const errorDomain = "blog_with_grpc.k-yomo.github.com"
+
+// ErrorInfo reasons clients can switch on, not found reasons are derived from the resource type, e.g. BLOG_NOT_FOUND
+const (
+	reasonInvalidID        = "INVALID_ID"
+	reasonValidationFailed = "VALIDATION_FAILED"
+	reasonStorageFailure   = "STORAGE_FAILURE"
+	reasonInternal         = "INTERNAL"
+	reasonRateLimited      = "RATE_LIMITED"
+
+	// UNAUTHENTICATED reasons
+	reasonAuthenticationRequired = "AUTHENTICATION_REQUIRED"
+	reasonInvalidToken           = "INVALID_TOKEN"
+	reasonInvalidAPIKey          = "INVALID_API_KEY"
+	reasonAPIKeyRevoked          = "API_KEY_REVOKED"
+
+	// PERMISSION_DENIED reasons
+	reasonMissingScope     = "MISSING_SCOPE"
+	reasonNotOwner         = "NOT_OWNER"
+	reasonAdminRequired    = "ADMIN_REQUIRED"
+	reasonAPIKeyNotAllowed = "API_KEY_NOT_ALLOWED"
+)
+
+const (
+	resourceBlog   = "blog"
+	resourceAPIKey = "api_key"
+)
+
+// withDetails attaches details to st, falling back to the bare status if they cannot be marshaled
+func withDetails(st *status.Status, details ...proto.Message) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func errorInfo(reason string, metadata map[string]string) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{Reason: reason, Domain: errorDomain, Metadata: metadata}
+}
+
+func notFoundError(resourceType, name string) error {
+	return withDetails(
+		status.Newf(codes.NotFound, "Cannot find %s %s", strings.Replace(resourceType, "_", " ", -1), name),
+		&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name},
+		errorInfo(strings.ToUpper(resourceType)+"_NOT_FOUND", map[string]string{"resource_type": resourceType}),
+	)
+}
+
+func invalidIDError(field, id string) error {
+	return withDetails(
+		status.Newf(codes.InvalidArgument, "Cannot parse %s %q", field, id),
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: "must be a 24 character hex object ID"}}},
+		errorInfo(reasonInvalidID, map[string]string{"field": field}),
+	)
+}
+
+func unauthenticatedError(reason, message string) error {
+	return withDetails(status.New(codes.Unauthenticated, message), errorInfo(reason, nil))
+}
+
+func permissionDeniedError(reason string, metadata map[string]string, message string) error {
+	return withDetails(status.New(codes.PermissionDenied, message), errorInfo(reason, metadata))
+}
+
+// missingScopeError names the scope in the ErrorInfo metadata, so a client knows which key or token to use instead
+func missingScopeError(sc scope) error {
+	return permissionDeniedError(reasonMissingScope, map[string]string{"scope": string(sc)}, "Credentials lack the "+string(sc)+" scope")
+}
+
+// internalError hides err from the client, it is logged with the request instead
+func internalError(ctx context.Context, reason string, err error) error {
+	ctxlogrus.AddFields(ctx, logrus.Fields{"internal_error": err.Error()})
+	return withDetails(status.New(codes.Internal, "Internal error"), errorInfo(reason, nil))
+}
+
+// storageError maps an error of a storage call on the named resource to a gRPC status
+func storageError(ctx context.Context, err error, resourceType, name string) error {
+	switch {
+	case err == nil:
+		return nil
+	case err == errNotFound || err == mongo.ErrNoDocuments:
+		return notFoundError(resourceType, name)
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return internalError(ctx, reasonStorageFailure, err)
+}
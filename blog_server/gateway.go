@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"net/http"
+	"strings"
+)
+
+// gatewayIncomingHeader forwards the HTTP headers the service reads as plain metadata keys,
+// other headers follow the grpc-gateway defaults
+func gatewayIncomingHeader(key string) (string, bool) {
+	switch k := strings.ToLower(key); k {
+	case "authorization", apiKeyHeader, requestIDHeader, idempotencyKeyHeader:
+		return k, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// gatewayOutgoingHeader returns the request ID as is and other metadata with the Grpc-Metadata- prefix
+func gatewayOutgoingHeader(key string) (string, bool) {
+	if key == requestIDHeader {
+		return key, true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// newGateway returns the REST/JSON handler proxying /v1/blogs to the gRPC server at grpcAddr,
+// so gateway calls pass the same auth, rate limiting and validation as native ones
+func newGateway(ctx context.Context, grpcAddr string) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(gatewayIncomingHeader),
+		runtime.WithOutgoingHeaderMatcher(gatewayOutgoingHeader),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{OrigName: true, EmitDefaults: true}),
+	)
+	opts := []grpc.DialOption{grpc.WithInsecure(), grpc.WithStatsHandler(otelgrpc.NewClientHandler())}
+	if err := blogpb.RegisterBlogServiceHandlerFromEndpoint(ctx, mux, grpcAddr, opts); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
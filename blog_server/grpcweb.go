@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+	"net/http"
+	"strings"
+)
+
+// grpcWebHeaders are the request headers browsers may send besides the ones gRPC-Web needs itself
+var grpcWebHeaders = []string{"authorization", apiKeyHeader, requestIDHeader, idempotencyKeyHeader}
+
+// parseOrigins splits a comma separated list of CORS origins, "*" allows any origin
+func parseOrigins(list string) map[string]bool {
+	origins := make(map[string]bool)
+	for _, o := range strings.Split(list, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins[strings.TrimSuffix(o, "/")] = true
+		}
+	}
+	return origins
+}
+
+// newGrpcWebHandler serves the services of s to browsers over gRPC-Web, server streams included,
+// answering CORS preflights for the allowed origins
+func newGrpcWebHandler(s *grpc.Server, allowedOrigins map[string]bool) http.Handler {
+	return grpcweb.WrapServer(s,
+		grpcweb.WithOriginFunc(func(origin string) bool {
+			return allowedOrigins["*"] || allowedOrigins[origin]
+		}),
+		grpcweb.WithAllowedRequestHeaders(grpcWebHeaders),
+	)
+}
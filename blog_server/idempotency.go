@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/mongodb/mongo-go-driver/x/bsonx"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+// idempotencyKeyHeader is the metadata key an idempotency key is read from when the request field is empty
+const idempotencyKeyHeader = "idempotency-key"
+
+const maxIdempotencyKeyLength = 255
+
+const (
+	reasonIdempotencyKeyReused     = "IDEMPOTENCY_KEY_REUSED"
+	reasonIdempotencyKeyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+)
+
+// idempotencyRecord remembers the blog created for an idempotency key
+type idempotencyRecord struct {
+	// ID is the caller ID and the key, keys of different callers never collide
+	ID          string             `bson:"_id"`
+	RequestHash string             `bson:"request_hash"`
+	BlogID      primitive.ObjectID `bson:"blog_id"`
+	Completed   bool               `bson:"completed"`
+	CreatedAt   time.Time          `bson:"created_at"`
+}
+
+// idempotencyRecords holds the records by ID
+type idempotencyRecords interface {
+	// insertIfAbsent stores rec unless its ID is taken, inserted tells which happened
+	insertIfAbsent(ctx context.Context, rec *idempotencyRecord) (inserted bool, err error)
+	// find returns errNotFound for an unknown ID
+	find(ctx context.Context, id string) (*idempotencyRecord, error)
+	// replaceIfCreatedAt replaces the record with rec if it was still created at createdAt
+	replaceIfCreatedAt(ctx context.Context, rec *idempotencyRecord, createdAt time.Time) (replaced bool, err error)
+	// renew moves created_at of an unfinished record from createdAt to renewedAt, if nobody changed it meanwhile
+	renew(ctx context.Context, id string, createdAt, renewedAt time.Time) (renewed bool, err error)
+	markCompleted(ctx context.Context, id string) error
+	remove(ctx context.Context, id string) error
+}
+
+// idempotencyStore remembers CreateBlog idempotency keys for ttl
+type idempotencyStore struct {
+	records idempotencyRecords
+	ttl     time.Duration
+	// lease is how long an unfinished claim blocks retries before it is assumed to be abandoned
+	lease time.Duration
+	blogs blogStore
+}
+
+// outcomeTimeout bounds recording the outcome of a claim, which must not fail because the RPC ended
+const outcomeTimeout = 5 * time.Second
+
+// outcomeContext keeps the span of ctx, but not its cancellation or deadline
+func outcomeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx)), outcomeTimeout)
+}
+
+// idempotencyKey returns the key of a CreateBlog call, preferring the request field over metadata
+func idempotencyKey(ctx context.Context, req interface{ GetIdempotencyKey() string }) string {
+	if key := req.GetIdempotencyKey(); key != "" {
+		return key
+	}
+	return metautils.ExtractIncoming(ctx).Get(idempotencyKeyHeader)
+}
+
+// hashBlogRequest digests the fields that make two CreateBlog calls the same request
+func hashBlogRequest(item *blogItem) string {
+	h := sha256.New()
+	for _, v := range []string{item.AuthorID, item.Title, item.Content, item.ContentFormat} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// claim records that rec.BlogID is being created for the key, returning the earlier record instead
+// if the key was already used within the window
+func (s *idempotencyStore) claim(ctx context.Context, rec *idempotencyRecord) (*idempotencyRecord, error) {
+	inserted, err := s.records.insertIfAbsent(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	if inserted {
+		return nil, nil
+	}
+
+	existing, err := s.records.find(ctx, rec.ID)
+	if err == errNotFound {
+		// expired between the two calls
+		return s.claim(ctx, rec)
+	} else if err != nil {
+		return nil, err
+	}
+	if !existing.Completed && existing.RequestHash == rec.RequestHash && time.Since(existing.CreatedAt) > s.lease {
+		return s.resume(ctx, existing, rec)
+	}
+	// the TTL monitor only runs every minute, a record past the window is taken over
+	if time.Since(existing.CreatedAt) > s.ttl {
+		replaced, err := s.records.replaceIfCreatedAt(ctx, rec, existing.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if replaced {
+			return nil, nil
+		}
+		return s.claim(ctx, rec)
+	}
+	return existing, nil
+}
+
+// resume settles a claim whose lease ran out, the call that made it crashed or could not record its outcome.
+// If its blog exists the claim is completed and returned for replay, otherwise rec takes it over.
+func (s *idempotencyStore) resume(ctx context.Context, existing, rec *idempotencyRecord) (*idempotencyRecord, error) {
+	_, err := s.blogs.findByID(ctx, existing.BlogID)
+	if err == nil {
+		if err := s.complete(ctx, existing.ID); err != nil {
+			return nil, err
+		}
+		existing.Completed = true
+		return existing, nil
+	}
+	if err != errNotFound {
+		return nil, err
+	}
+
+	// the blog ID is kept, so should the abandoned call still insert, one of the two inserts fails
+	renewed, err := s.records.renew(ctx, existing.ID, existing.CreatedAt, rec.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if !renewed {
+		// another retry settled it first
+		return s.claim(ctx, rec)
+	}
+	rec.BlogID = existing.BlogID
+	return nil, nil
+}
+
+// complete marks a claim as done, it outlives ctx as it is needed most when the RPC was cancelled
+func (s *idempotencyStore) complete(ctx context.Context, id string) error {
+	ctx, cancel := outcomeContext(ctx)
+	defer cancel()
+	return s.records.markCompleted(ctx, id)
+}
+
+// release forgets a claim whose blog could not be created, so the call can be retried with the same key.
+// Like complete, it outlives ctx, whose end is often why the insert failed.
+func (s *idempotencyStore) release(ctx context.Context, id string) error {
+	ctx, cancel := outcomeContext(ctx)
+	defer cancel()
+	return s.records.remove(ctx, id)
+}
+
+// mongoIdempotencyRecords keeps the records in a collection, MongoDB expires them through a TTL index
+type mongoIdempotencyRecords struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoIdempotencyRecords) ensureIndexes(ctx context.Context, ttl time.Duration) (err error) {
+	ctx, done := startOp(ctx, idempotencyCollection, "create_index")
+	defer done(&err)
+	_, err = r.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bsonx.Doc{{Key: "created_at", Value: bsonx.Int32(1)}},
+		Options: options.Index().SetExpireAfterSeconds(int32(ttl / time.Second)),
+	})
+	return err
+}
+
+func (r *mongoIdempotencyRecords) insertIfAbsent(ctx context.Context, rec *idempotencyRecord) (inserted bool, err error) {
+	ctx, done := startOp(ctx, idempotencyCollection, "upsert")
+	defer done(&err)
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": rec.ID}, bson.M{"$setOnInsert": rec}, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return res.UpsertedID != nil, nil
+}
+
+func (r *mongoIdempotencyRecords) find(ctx context.Context, id string) (rec *idempotencyRecord, err error) {
+	ctx, done := startOp(ctx, idempotencyCollection, "find_one")
+	defer done(&err)
+	rec = &idempotencyRecord{}
+	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(rec); err == mongo.ErrNoDocuments {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (r *mongoIdempotencyRecords) replaceIfCreatedAt(ctx context.Context, rec *idempotencyRecord, createdAt time.Time) (replaced bool, err error) {
+	ctx, done := startOp(ctx, idempotencyCollection, "replace")
+	defer done(&err)
+	res, err := r.coll.ReplaceOne(ctx, bson.M{"_id": rec.ID, "created_at": createdAt}, rec)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func (r *mongoIdempotencyRecords) renew(ctx context.Context, id string, createdAt, renewedAt time.Time) (renewed bool, err error) {
+	ctx, done := startOp(ctx, idempotencyCollection, "update")
+	defer done(&err)
+	res, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": id, "created_at": createdAt, "completed": false},
+		bson.M{"$set": bson.M{"created_at": renewedAt}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func (r *mongoIdempotencyRecords) markCompleted(ctx context.Context, id string) (err error) {
+	ctx, done := startOp(ctx, idempotencyCollection, "update")
+	defer done(&err)
+	_, err = r.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"completed": true}})
+	return err
+}
+
+func (r *mongoIdempotencyRecords) remove(ctx context.Context, id string) (err error) {
+	ctx, done := startOp(ctx, idempotencyCollection, "delete")
+	defer done(&err)
+	_, err = r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// replayError explains why an earlier record cannot be replayed for the request hashed to requestHash, or returns nil
+func (rec *idempotencyRecord) replayError(requestHash string) error {
+	if rec.RequestHash != requestHash {
+		return withDetails(
+			status.New(codes.InvalidArgument, "Idempotency key was already used for a different blog"),
+			errorInfo(reasonIdempotencyKeyReused, nil),
+		)
+	}
+	if !rec.Completed {
+		return withDetails(
+			status.New(codes.Aborted, "A request with this idempotency key is still in progress, retry later"),
+			errorInfo(reasonIdempotencyKeyInProgress, nil),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryIdempotencyRecords is an idempotencyRecords in a map.
+// The hooks run before the operation of their name, so a test can change the records the way a concurrent call would.
+type memoryIdempotencyRecords struct {
+	mu          sync.Mutex
+	records     map[string]idempotencyRecord
+	beforeFind  func(records map[string]idempotencyRecord)
+	beforeRenew func(records map[string]idempotencyRecord)
+}
+
+func newMemoryIdempotencyRecords(records ...*idempotencyRecord) *memoryIdempotencyRecords {
+	m := &memoryIdempotencyRecords{records: map[string]idempotencyRecord{}}
+	for _, rec := range records {
+		m.records[rec.ID] = *rec
+	}
+	return m
+}
+
+func (m *memoryIdempotencyRecords) insertIfAbsent(ctx context.Context, rec *idempotencyRecord) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.records[rec.ID]; ok {
+		return false, nil
+	}
+	m.records[rec.ID] = *rec
+	return true, nil
+}
+
+func (m *memoryIdempotencyRecords) find(ctx context.Context, id string) (*idempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.beforeFind != nil {
+		m.beforeFind(m.records)
+		m.beforeFind = nil
+	}
+	rec, ok := m.records[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	return &rec, nil
+}
+
+func (m *memoryIdempotencyRecords) replaceIfCreatedAt(ctx context.Context, rec *idempotencyRecord, createdAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.records[rec.ID]; !ok || !existing.CreatedAt.Equal(createdAt) {
+		return false, nil
+	}
+	m.records[rec.ID] = *rec
+	return true, nil
+}
+
+func (m *memoryIdempotencyRecords) renew(ctx context.Context, id string, createdAt, renewedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.beforeRenew != nil {
+		m.beforeRenew(m.records)
+		m.beforeRenew = nil
+	}
+	rec, ok := m.records[id]
+	if !ok || rec.Completed || !rec.CreatedAt.Equal(createdAt) {
+		return false, nil
+	}
+	rec.CreatedAt = renewedAt
+	m.records[id] = rec
+	return true, nil
+}
+
+func (m *memoryIdempotencyRecords) markCompleted(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rec, ok := m.records[id]; ok {
+		rec.Completed = true
+		m.records[id] = rec
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyRecords) remove(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, id)
+	return nil
+}
+
+func (m *memoryIdempotencyRecords) get(id string) (idempotencyRecord, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.records[id]
+	return rec, ok
+}
+
+const (
+	testKey  = "alice:key-1"
+	testHash = "hash-of-the-request"
+)
+
+// record returns a claim of testKey for blog made age ago
+func record(t *testing.T, blog int, age time.Duration, completed bool, hash string) *idempotencyRecord {
+	return &idempotencyRecord{ID: testKey, RequestHash: hash, BlogID: testID(t, blog), Completed: completed, CreatedAt: time.Now().UTC().Add(-age)}
+}
+
+func reasonOf(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+func TestIdempotencyClaim(t *testing.T) {
+	const (
+		ttl   = time.Hour
+		lease = time.Minute
+	)
+	tests := []struct {
+		name string
+		// existing is the record of testKey before the claim, if any
+		existing *idempotencyRecord
+		// blogExists stores blog 1, the blog of existing
+		blogExists bool
+		hash       string
+		// wantExisting is the blog of the record returned for replay, 0 when the claim is granted
+		wantExisting int
+		// wantBlog is the blog the granted claim creates
+		wantBlog   int
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{name: "new key", hash: testHash, wantBlog: 2},
+		{
+			name:         "completed request is replayed",
+			existing:     record(t, 1, time.Second, true, testHash),
+			hash:         testHash,
+			wantExisting: 1,
+		},
+		{
+			name:         "key reused for another request",
+			existing:     record(t, 1, time.Second, true, testHash),
+			hash:         "hash-of-another-request",
+			wantExisting: 1,
+			wantCode:     codes.InvalidArgument,
+			wantReason:   reasonIdempotencyKeyReused,
+		},
+		{
+			name:         "request still in progress",
+			existing:     record(t, 1, time.Second, false, testHash),
+			hash:         testHash,
+			wantExisting: 1,
+			wantCode:     codes.Aborted,
+			wantReason:   reasonIdempotencyKeyInProgress,
+		},
+		{
+			name:         "lease ran out after the blog was created",
+			existing:     record(t, 1, 2*lease, false, testHash),
+			blogExists:   true,
+			hash:         testHash,
+			wantExisting: 1,
+		},
+		{
+			name:     "lease ran out before the blog was created",
+			existing: record(t, 1, 2*lease, false, testHash),
+			hash:     testHash,
+			// the claim is taken over with the ID of the abandoned call
+			wantBlog: 1,
+		},
+		{
+			name:     "record past the window",
+			existing: record(t, 1, 2*ttl, true, testHash),
+			hash:     "hash-of-another-request",
+			wantBlog: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			records := newMemoryIdempotencyRecords()
+			if tt.existing != nil {
+				records = newMemoryIdempotencyRecords(tt.existing)
+			}
+			blogs := testSnapshot(t, nil)
+			if tt.blogExists {
+				blogs = testSnapshot(t, map[int]string{1: "created"})
+			}
+			s := &idempotencyStore{records: records, ttl: ttl, lease: lease, blogs: blogs}
+
+			rec := &idempotencyRecord{ID: testKey, RequestHash: tt.hash, BlogID: testID(t, 2), CreatedAt: time.Now().UTC()}
+			got, err := s.claim(ctx, rec)
+			if err != nil {
+				t.Fatalf("claim() error = %v", err)
+			}
+
+			if tt.wantExisting == 0 {
+				if got != nil {
+					t.Fatalf("claim() = %+v, want the claim granted", got)
+				}
+				if rec.BlogID != testID(t, tt.wantBlog) {
+					t.Errorf("claim creates blog %s, want %s", rec.BlogID.Hex(), testID(t, tt.wantBlog).Hex())
+				}
+				stored, _ := records.get(testKey)
+				if stored.Completed || stored.RequestHash != tt.hash || time.Since(stored.CreatedAt) > lease {
+					t.Errorf("stored record = %+v, want a fresh unfinished claim", stored)
+				}
+				return
+			}
+
+			if got == nil {
+				t.Fatal("claim() granted the claim, want the earlier record")
+			}
+			if got.BlogID != testID(t, tt.wantExisting) {
+				t.Errorf("claim() returned blog %s, want %s", got.BlogID.Hex(), testID(t, tt.wantExisting).Hex())
+			}
+			err = got.replayError(tt.hash)
+			if status.Code(err) != tt.wantCode || reasonOf(err) != tt.wantReason {
+				t.Errorf("replayError() = %v, want %v %s", err, tt.wantCode, tt.wantReason)
+			}
+			if tt.blogExists {
+				if stored, _ := records.get(testKey); !stored.Completed {
+					t.Error("the settled claim was not marked completed")
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyClaimRaces(t *testing.T) {
+	ctx := context.Background()
+	newClaim := func() *idempotencyRecord {
+		return &idempotencyRecord{ID: testKey, RequestHash: testHash, BlogID: testID(t, 2), CreatedAt: time.Now().UTC()}
+	}
+
+	t.Run("record expires between insert and find", func(t *testing.T) {
+		records := newMemoryIdempotencyRecords(record(t, 1, time.Second, true, testHash))
+		records.beforeFind = func(r map[string]idempotencyRecord) { delete(r, testKey) }
+		s := &idempotencyStore{records: records, ttl: time.Hour, lease: time.Minute, blogs: testSnapshot(t, nil)}
+		if got, err := s.claim(ctx, newClaim()); err != nil || got != nil {
+			t.Fatalf("claim() = %+v, %v, want the claim granted", got, err)
+		}
+	})
+
+	t.Run("another retry takes over the abandoned claim first", func(t *testing.T) {
+		records := newMemoryIdempotencyRecords(record(t, 1, 2*time.Minute, false, testHash))
+		records.beforeRenew = func(r map[string]idempotencyRecord) {
+			rec := r[testKey]
+			rec.CreatedAt = time.Now().UTC()
+			r[testKey] = rec
+		}
+		s := &idempotencyStore{records: records, ttl: time.Hour, lease: time.Minute, blogs: testSnapshot(t, nil)}
+		got, err := s.claim(ctx, newClaim())
+		if err != nil {
+			t.Fatalf("claim() error = %v", err)
+		}
+		if got == nil || reasonOf(got.replayError(testHash)) != reasonIdempotencyKeyInProgress {
+			t.Fatalf("claim() = %+v, want the claim of the other retry in progress", got)
+		}
+	})
+}
+
+func TestIdempotencyReleaseAndComplete(t *testing.T) {
+	records := newMemoryIdempotencyRecords(record(t, 1, time.Second, false, testHash))
+	s := &idempotencyStore{records: records, ttl: time.Hour, lease: time.Minute}
+
+	// the RPC context has ended, the outcome is still recorded
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.complete(ctx, testKey); err != nil {
+		t.Fatalf("complete() error = %v", err)
+	}
+	if rec, _ := records.get(testKey); !rec.Completed {
+		t.Error("complete() did not mark the record")
+	}
+	if err := s.release(ctx, testKey); err != nil {
+		t.Fatalf("release() error = %v", err)
+	}
+	if _, ok := records.get(testKey); ok {
+		t.Error("release() kept the record")
+	}
+}
+
+// failingStore fails every insert
+type failingStore struct {
+	blogStore
+}
+
+func (failingStore) insert(ctx context.Context, item *blogItem) error {
+	return errors.New("disk full")
+}
+
+func TestCreateBlogIdempotency(t *testing.T) {
+	ctx := withCaller(context.Background(), &caller{ID: "alice", Role: roleAuthor, Scopes: scopesOf(roleAuthor)})
+	req := &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "Title", Content: "Content"}, IdempotencyKey: "key-1"}
+
+	t.Run("a retry replays the first response", func(t *testing.T) {
+		blogs := testSnapshot(t, nil)
+		records := newMemoryIdempotencyRecords()
+		s := &server{store: blogs, idempotency: &idempotencyStore{records: records, ttl: time.Hour, lease: time.Minute, blogs: blogs}}
+
+		first, err := s.CreateBlog(ctx, req)
+		if err != nil {
+			t.Fatalf("CreateBlog() error = %v", err)
+		}
+		second, err := s.CreateBlog(ctx, req)
+		if err != nil {
+			t.Fatalf("retried CreateBlog() error = %v", err)
+		}
+		if second.GetBlog().GetId() != first.GetBlog().GetId() {
+			t.Errorf("retry created blog %s, want %s", second.GetBlog().GetId(), first.GetBlog().GetId())
+		}
+		if items, _ := blogs.page(ctx, nil, 0); len(items) != 1 {
+			t.Errorf("%d blogs stored, want 1", len(items))
+		}
+		if rec, _ := records.get("alice:key-1"); !rec.Completed {
+			t.Error("the claim was not completed")
+		}
+	})
+
+	t.Run("a failed insert releases the key", func(t *testing.T) {
+		records := newMemoryIdempotencyRecords()
+		s := &server{store: failingStore{}, idempotency: &idempotencyStore{records: records, ttl: time.Hour, lease: time.Minute}}
+		if _, err := s.CreateBlog(ctx, req); status.Code(err) != codes.Internal {
+			t.Fatalf("CreateBlog() error = %v, want INTERNAL", err)
+		}
+		if _, ok := records.get("alice:key-1"); ok {
+			t.Error("the claim of the failed call was kept")
+		}
+	})
+}
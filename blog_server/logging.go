@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// requestIDHeader is the metadata key a request ID is read from and echoed back in
+	requestIDHeader = "x-request-id"
+	requestIDTag    = "request_id"
+	callerTag       = "caller"
+)
+
+// newLogger returns a JSON logger writing to stderr at the given level
+func newLogger(level string) (*logrus.Logger, error) {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	return &logrus.Logger{
+		Out:       os.Stderr,
+		Formatter: &logrus.JSONFormatter{},
+		Hooks:     make(logrus.LevelHooks),
+		Level:     lvl,
+	}, nil
+}
+
+// assignRequestID tags the call with the request ID sent by the client, or a new one if there is none
+func assignRequestID(ctx context.Context) (string, metadata.MD) {
+	id := metautils.ExtractIncoming(ctx).Get(requestIDHeader)
+	if id == "" {
+		id = newRequestID()
+	}
+	grpc_ctxtags.Extract(ctx).Set(requestIDTag, id)
+	return id, metadata.Pairs(requestIDHeader, id)
+}
+
+// requestIDFallback numbers the IDs made without randomness, so they stay distinct within the process
+var requestIDFallback uint64
+
+// newRequestID returns 16 random bytes in hex, or the time and a counter if the system has no randomness to give
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixNano()))
+		binary.BigEndian.PutUint64(b[8:], atomic.AddUint64(&requestIDFallback, 1))
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestIDFromContext returns the ID assigned to the current call
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := grpc_ctxtags.Extract(ctx).Values()[requestIDTag].(string)
+	return id
+}
+
+func requestIDUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	_, md := assignRequestID(ctx)
+	grpc.SetHeader(ctx, md)
+	return handler(ctx, req)
+}
+
+func requestIDStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	_, md := assignRequestID(stream.Context())
+	stream.SetHeader(md)
+	return handler(srv, stream)
+}
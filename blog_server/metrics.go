@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+var (
+	rpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blog_grpc_requests_total",
+		Help: "Number of RPCs handled, by method and status code.",
+	}, []string{"method", "code"})
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "blog_grpc_request_duration_seconds",
+		Help:    "Latency of RPCs until the response or the end of the stream.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+
+	streamsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "blog_grpc_streams_in_flight",
+		Help: "Number of server streams currently open, such as ListBlog.",
+	}, []string{"method"})
+
+	streamMessagesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blog_grpc_stream_messages_sent_total",
+		Help: "Number of messages sent on server streams.",
+	}, []string{"method"})
+
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "blog_storage_operation_duration_seconds",
+		Help:    "Latency of MongoDB operations, by collection, operation and result.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"collection", "operation", "result"})
+
+	panicsRecovered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blog_grpc_panics_recovered_total",
+		Help: "Number of handler and interceptor panics turned into INTERNAL errors, by method.",
+	}, []string{"method"})
+)
+
+func init() {
+	prometheus.MustRegister(rpcRequests, rpcDuration, streamsInFlight, streamMessagesSent, storageDuration, panicsRecovered)
+}
+
+// observeStorage records how long a storage operation took and how it ended
+func observeStorage(collection, op string, d time.Duration, err error) {
+	result := "ok"
+	switch {
+	case isNotFound(err):
+		result = "not_found"
+	case err != nil:
+		result = "error"
+	}
+	storageDuration.WithLabelValues(collection, op, result).Observe(d.Seconds())
+}
+
+func metricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	rpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	rpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+func metricsStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	inFlight := streamsInFlight.WithLabelValues(info.FullMethod)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	err := handler(srv, &countingServerStream{ServerStream: stream, sent: streamMessagesSent.WithLabelValues(info.FullMethod)})
+	rpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	rpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// countingServerStream counts the messages successfully sent on the stream
+type countingServerStream struct {
+	grpc.ServerStream
+	sent prometheus.Counter
+}
+
+func (s *countingServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Inc()
+	}
+	return err
+}
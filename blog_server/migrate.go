@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// openStore opens the backend named by spec:
+// mongodb://host:port/database for the blog collection of a MongoDB database, blog_with_grpc by default,
+// or snapshot:path for a snapshot file. closeStore saves snapshots and disconnects from MongoDB.
+func openStore(ctx context.Context, spec string) (store blogStore, closeStore func(context.Context) error, err error) {
+	switch {
+	case strings.HasPrefix(spec, "mongodb://"):
+		u, err := url.Parse(spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		database := strings.TrimPrefix(u.Path, "/")
+		if database == "" {
+			database = "blog_with_grpc"
+		}
+		client, err := mongo.NewClient(spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		connectCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if err := client.Connect(connectCtx); err != nil {
+			return nil, nil, err
+		}
+		return &mongoStore{coll: client.Database(database).Collection("blog")}, client.Disconnect, nil
+	case strings.HasPrefix(spec, "snapshot:"):
+		s, err := openSnapshot(strings.TrimPrefix(spec, "snapshot:"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, func(context.Context) error { return s.save() }, nil
+	}
+	return nil, nil, fmt.Errorf("unknown storage %q, use mongodb://host:port/database or snapshot:path", spec)
+}
+
+// storeCursor walks a store in ID order, a page at a time
+type storeCursor struct {
+	store     blogStore
+	batchSize int64
+	buf       []*blogItem
+	exhausted bool
+}
+
+// peek returns the first blog with an ID greater than after, nil at the end.
+// Pages are fetched after the last ID processed rather than the last one read,
+// so blogs the migration itself inserts into the store being walked are not seen.
+func (c *storeCursor) peek(ctx context.Context, after *primitive.ObjectID) (*blogItem, error) {
+	for len(c.buf) > 0 && after != nil && !idLess(*after, c.buf[0].ID) {
+		c.buf = c.buf[1:]
+	}
+	if len(c.buf) == 0 && !c.exhausted {
+		items, err := c.store.page(ctx, after, c.batchSize)
+		if err != nil {
+			return nil, err
+		}
+		c.buf = items
+		c.exhausted = int64(len(items)) < c.batchSize
+	}
+	if len(c.buf) == 0 {
+		return nil, nil
+	}
+	return c.buf[0], nil
+}
+
+// migrateStats counts what a migration did, or would do in a dry run
+type migrateStats struct {
+	copied, updated, unchanged, deleted, extra int
+}
+
+// migrate makes dst hold the blogs of src. Blogs that are already identical are not written,
+// so a re-run only copies what changed since the last one. Blogs only in dst are deleted if deleteExtra is set.
+func migrate(ctx context.Context, src, dst blogStore, batchSize int64, deleteExtra, dryRun bool) (*migrateStats, error) {
+	stats := &migrateStats{}
+	srcCursor := &storeCursor{store: src, batchSize: batchSize}
+	dstCursor := &storeCursor{store: dst, batchSize: batchSize}
+	var last *primitive.ObjectID
+	for {
+		s, err := srcCursor.peek(ctx, last)
+		if err != nil {
+			return stats, fmt.Errorf("cannot read source: %v", err)
+		}
+		d, err := dstCursor.peek(ctx, last)
+		if err != nil {
+			return stats, fmt.Errorf("cannot read destination: %v", err)
+		}
+
+		var id primitive.ObjectID
+		switch {
+		case s == nil && d == nil:
+			return stats, nil
+		case d == nil || s != nil && idLess(s.ID, d.ID):
+			id = s.ID
+			stats.copied++
+			if !dryRun {
+				if _, err := dst.upsert(ctx, s); err != nil {
+					return stats, fmt.Errorf("cannot write %s: %v", id.Hex(), err)
+				}
+			}
+		case s == nil || idLess(d.ID, s.ID):
+			id = d.ID
+			if !deleteExtra {
+				stats.extra++
+				break
+			}
+			stats.deleted++
+			if !dryRun {
+				if err := dst.deleteByID(ctx, id); err != nil && err != errNotFound {
+					return stats, fmt.Errorf("cannot delete %s: %v", id.Hex(), err)
+				}
+			}
+		default:
+			id = s.ID
+			if *s == *d {
+				stats.unchanged++
+				break
+			}
+			stats.updated++
+			if !dryRun {
+				if _, err := dst.upsert(ctx, s); err != nil {
+					return stats, fmt.Errorf("cannot write %s: %v", id.Hex(), err)
+				}
+			}
+		}
+		last = &id
+	}
+}
+
+// storeChecksum returns the number of blogs and a SHA-256 over them in ID order
+func storeChecksum(ctx context.Context, store blogStore, batchSize int64) (int64, string, error) {
+	h := sha256.New()
+	count := int64(0)
+	var after *primitive.ObjectID
+	for {
+		items, err := store.page(ctx, after, batchSize)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, item := range items {
+			h.Write(item.ID[:])
+			for _, v := range []string{item.AuthorID, item.Title, item.Content, item.ContentFormat} {
+				var size [8]byte
+				binary.BigEndian.PutUint64(size[:], uint64(len(v)))
+				h.Write(size[:])
+				h.Write([]byte(v))
+			}
+			count++
+		}
+		if int64(len(items)) < batchSize {
+			return count, hex.EncodeToString(h.Sum(nil)), nil
+		}
+		after = &items[len(items)-1].ID
+	}
+}
+
+// runMigrate implements blog_server migrate
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := fs.String("from", "mongodb://localhost:27017/blog_with_grpc", "storage to copy blogs from")
+	to := fs.String("to", "", "storage to copy blogs to, mongodb://host:port/database or snapshot:path")
+	batchSize := fs.Int64("batch-size", 500, "blogs read per round trip")
+	deleteExtra := fs.Bool("delete", false, "delete blogs in the destination that are not in the source")
+	dryRun := fs.Bool("dry-run", false, "report what would change without writing")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: blog_server migrate -from <storage> -to <storage> [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *to == "" {
+		fs.Usage()
+		return errors.New("-to is required")
+	}
+	if *batchSize <= 0 {
+		return errors.New("-batch-size must be positive")
+	}
+
+	ctx := context.Background()
+	src, closeSrc, err := openStore(ctx, *from)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %v", *from, err)
+	}
+	defer closeSrc(ctx)
+	dst, closeDst, err := openStore(ctx, *to)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %v", *to, err)
+	}
+
+	stats, err := migrate(ctx, src, dst, *batchSize, *deleteExtra, *dryRun)
+	// whatever was copied is kept, so a re-run continues from there
+	if closeErr := closeDst(ctx); err == nil && closeErr != nil {
+		err = fmt.Errorf("cannot save %s: %v", *to, closeErr)
+	}
+	prefix := ""
+	if *dryRun {
+		prefix = "dry run: "
+	}
+	fmt.Printf("%s%d copied, %d updated, %d unchanged, %d deleted, %d only in the destination\n",
+		prefix, stats.copied, stats.updated, stats.unchanged, stats.deleted, stats.extra)
+	if err != nil || *dryRun {
+		return err
+	}
+
+	// the destination is reopened so a snapshot is verified as it was saved
+	dst, closeDst, err = openStore(ctx, *to)
+	if err != nil {
+		return fmt.Errorf("cannot reopen %s: %v", *to, err)
+	}
+	defer closeDst(ctx)
+	srcCount, srcSum, err := storeChecksum(ctx, src, *batchSize)
+	if err != nil {
+		return fmt.Errorf("cannot checksum source: %v", err)
+	}
+	dstCount, dstSum, err := storeChecksum(ctx, dst, *batchSize)
+	if err != nil {
+		return fmt.Errorf("cannot checksum destination: %v", err)
+	}
+	fmt.Printf("source:      %d blogs, sha256 %s\ndestination: %d blogs, sha256 %s\n", srcCount, srcSum, dstCount, dstSum)
+	if srcCount != dstCount || srcSum != dstSum {
+		if stats.extra > 0 {
+			return errors.New("verification failed, the destination has blogs the source does not, re-run with -delete to remove them")
+		}
+		// the source changed while it was copied, a re-run catches up
+		return errors.New("verification failed, re-run the migration to copy the changes made meanwhile")
+	}
+	fmt.Println("Verified")
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testID(t *testing.T, i int) primitive.ObjectID {
+	id, err := primitive.ObjectIDFromHex(fmt.Sprintf("%024x", i))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// testSnapshot opens an empty snapshot in a temporary directory and inserts a blog titled title for each entry
+func testSnapshot(t *testing.T, titles map[int]string) *snapshotStore {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	s, err := openSnapshot(filepath.Join(dir, "blogs.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, title := range titles {
+		item := &blogItem{ID: testID(t, i), AuthorID: "author", Title: title, Content: "content", ContentFormat: "markdown"}
+		if err := s.insert(context.Background(), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// snapshotTitles is the inverse of testSnapshot
+func snapshotTitles(t *testing.T, s *snapshotStore) map[int]string {
+	items, err := s.page(context.Background(), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := map[int]string{}
+	for _, item := range items {
+		var i int
+		if _, err := fmt.Sscanf(item.ID.Hex(), "%x", &i); err != nil {
+			t.Fatal(err)
+		}
+		titles[i] = item.Title
+	}
+	return titles
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		src, dst    map[int]string
+		deleteExtra bool
+		dryRun      bool
+		wantStats   migrateStats
+		wantDst     map[int]string
+	}{
+		{
+			name:      "copy into an empty destination",
+			src:       map[int]string{1: "a", 2: "b", 3: "c", 4: "d", 5: "e"},
+			dst:       map[int]string{},
+			wantStats: migrateStats{copied: 5},
+			wantDst:   map[int]string{1: "a", 2: "b", 3: "c", 4: "d", 5: "e"},
+		},
+		{
+			name:      "empty source",
+			src:       map[int]string{},
+			dst:       map[int]string{},
+			wantStats: migrateStats{},
+			wantDst:   map[int]string{},
+		},
+		{
+			name:      "update changed blogs and copy missing ones",
+			src:       map[int]string{1: "a", 2: "b2", 3: "c", 4: "d2", 5: "e"},
+			dst:       map[int]string{1: "a", 2: "b", 4: "d", 5: "e"},
+			wantStats: migrateStats{copied: 1, updated: 2, unchanged: 2},
+			wantDst:   map[int]string{1: "a", 2: "b2", 3: "c", 4: "d2", 5: "e"},
+		},
+		{
+			name:      "blogs only in the destination are kept without -delete",
+			src:       map[int]string{2: "b", 4: "d"},
+			dst:       map[int]string{1: "x", 2: "b", 3: "y", 5: "z"},
+			wantStats: migrateStats{copied: 1, unchanged: 1, extra: 3},
+			wantDst:   map[int]string{1: "x", 2: "b", 3: "y", 4: "d", 5: "z"},
+		},
+		{
+			name:        "-delete removes blogs only in the destination",
+			src:         map[int]string{2: "b", 4: "d"},
+			dst:         map[int]string{1: "x", 2: "b", 3: "y", 5: "z"},
+			deleteExtra: true,
+			wantStats:   migrateStats{copied: 1, unchanged: 1, deleted: 3},
+			wantDst:     map[int]string{2: "b", 4: "d"},
+		},
+		{
+			name:        "-delete empties the destination of an empty source",
+			src:         map[int]string{},
+			dst:         map[int]string{1: "x", 2: "y", 3: "z"},
+			deleteExtra: true,
+			wantStats:   migrateStats{deleted: 3},
+			wantDst:     map[int]string{},
+		},
+		{
+			name:        "dry run writes nothing",
+			src:         map[int]string{1: "a", 2: "b2"},
+			dst:         map[int]string{2: "b", 3: "y"},
+			deleteExtra: true,
+			dryRun:      true,
+			wantStats:   migrateStats{copied: 1, updated: 1, deleted: 1},
+			wantDst:     map[int]string{2: "b", 3: "y"},
+		},
+	}
+	for _, tt := range tests {
+		// a batch smaller than the data makes the walk cross page boundaries
+		for _, batchSize := range []int64{2, 100} {
+			t.Run(fmt.Sprintf("%s/batch %d", tt.name, batchSize), func(t *testing.T) {
+				ctx := context.Background()
+				src, dst := testSnapshot(t, tt.src), testSnapshot(t, tt.dst)
+				stats, err := migrate(ctx, src, dst, batchSize, tt.deleteExtra, tt.dryRun)
+				if err != nil {
+					t.Fatalf("migrate() error = %v", err)
+				}
+				if *stats != tt.wantStats {
+					t.Errorf("migrate() stats = %+v, want %+v", *stats, tt.wantStats)
+				}
+				if got := snapshotTitles(t, dst); !reflect.DeepEqual(got, tt.wantDst) {
+					t.Errorf("destination = %v, want %v", got, tt.wantDst)
+				}
+				if tt.dryRun {
+					return
+				}
+
+				// a re-run has nothing left to write
+				stats, err = migrate(ctx, src, dst, batchSize, tt.deleteExtra, false)
+				if err != nil {
+					t.Fatalf("second migrate() error = %v", err)
+				}
+				if stats.copied != 0 || stats.updated != 0 || stats.deleted != 0 {
+					t.Errorf("second migrate() stats = %+v, want no writes", *stats)
+				}
+				if stats.unchanged != len(tt.src) {
+					t.Errorf("second migrate() unchanged = %d, want %d", stats.unchanged, len(tt.src))
+				}
+			})
+		}
+	}
+}
+
+func TestMigrateVerifiesAfterSave(t *testing.T) {
+	ctx := context.Background()
+	src := testSnapshot(t, map[int]string{1: "a", 2: "b", 3: "c"})
+	dst := testSnapshot(t, map[int]string{2: "old", 4: "extra"})
+	if _, err := migrate(ctx, src, dst, 2, true, false); err != nil {
+		t.Fatalf("migrate() error = %v", err)
+	}
+	if err := dst.save(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := openSnapshot(dst.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcCount, srcSum, err := storeChecksum(ctx, src, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstCount, dstSum, err := storeChecksum(ctx, reopened, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srcCount != 3 || dstCount != srcCount || dstSum != srcSum {
+		t.Errorf("checksums = %d %s and %d %s, want 3 blogs with equal sums", srcCount, srcSum, dstCount, dstSum)
+	}
+
+	// a blog changed after the copy shows up in the checksum
+	if err := src.replace(ctx, &blogItem{ID: testID(t, 2), AuthorID: "author", Title: "b2", Content: "content", ContentFormat: "markdown"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, changedSum, err := storeChecksum(ctx, src, 2); err != nil || changedSum == dstSum {
+		t.Errorf("checksum after a change = %s, %v, want it to differ from %s", changedSum, err, dstSum)
+	}
+}
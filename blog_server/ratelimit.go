@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+type limitClass string
+
+const (
+	limitRead   limitClass = "read"
+	limitWrite  limitClass = "write"
+	limitStream limitClass = "stream"
+	// limitRestore is separate from writes, a restore sends one RestoreBlog per blog of the backup
+	limitRestore limitClass = "restore"
+	// limitAuth counts failed authentications per peer, it is checked before credentials are
+	limitAuth limitClass = "auth"
+)
+
+// methodClasses assigns every RPC to the bucket it draws from
+var methodClasses = map[string]limitClass{
+	"/blog.BlogService/ReadBlog":      limitRead,
+	"/blog.BlogService/ListBlog":      limitStream,
+	"/blog.BlogService/ListBlogs":     limitRead,
+	"/apikey.ApiKeyService/ListKeys":  limitRead,
+	"/blog.BlogService/CreateBlog":    limitWrite,
+	"/blog.BlogService/UpdateBlog":    limitWrite,
+	"/blog.BlogService/DeleteBlog":    limitWrite,
+	"/blog.BlogService/RestoreBlog":   limitRestore,
+	"/apikey.ApiKeyService/CreateKey": limitWrite,
+	"/apikey.ApiKeyService/RevokeKey": limitWrite,
+}
+
+// rateLimit is the sustained rate and burst of one token bucket
+type rateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// checkLimits rejects limits whose bucket could never hold a token
+func checkLimits(limits map[limitClass]rateLimit) error {
+	for class, limit := range limits {
+		if limit.PerSecond > 0 && limit.Burst < 1 {
+			return fmt.Errorf("burst of the %s limit must be at least 1 when its rate is set", class)
+		}
+	}
+	return nil
+}
+
+// idleBucketTTL is how long the bucket of a caller who stopped calling is kept around
+const idleBucketTTL = 10 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter keeps one token bucket per caller and limit class
+type rateLimiter struct {
+	limits map[limitClass]rateLimit
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(limits map[limitClass]rateLimit) *rateLimiter {
+	return &rateLimiter{
+		limits:    limits,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// limiter returns the bucket of the caller in the class, nil when the class is not limited
+func (l *rateLimiter) limiter(key string, class limitClass, now time.Time) *rate.Limiter {
+	limit, ok := l.limits[class]
+	if !ok || limit.PerSecond <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > idleBucketTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	k := string(class) + "|" + key
+	b, ok := l.buckets[k]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.PerSecond), limit.Burst)}
+		l.buckets[k] = b
+	}
+	b.lastSeen = now
+	return b.limiter
+}
+
+// reserve takes a token for the caller, returning how long to wait before retrying if none is left
+func (l *rateLimiter) reserve(key string, class limitClass) (bool, time.Duration) {
+	now := time.Now()
+	lim := l.limiter(key, class, now)
+	if lim == nil {
+		return true, 0
+	}
+	r := lim.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Second
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// wait returns how long until the caller has a token again, without taking it
+func (l *rateLimiter) wait(key string, class limitClass) time.Duration {
+	now := time.Now()
+	lim := l.limiter(key, class, now)
+	if lim == nil {
+		return 0
+	}
+	r := lim.ReserveN(now, 1)
+	if !r.OK() {
+		return time.Second
+	}
+	defer r.CancelAt(now)
+	return r.DelayFrom(now)
+}
+
+// methodClass returns the class of an RPC, unknown methods count as writes
+func methodClass(fullMethod string) limitClass {
+	if class, ok := methodClasses[fullMethod]; ok {
+		return class
+	}
+	return limitWrite
+}
+
+func (l *rateLimiter) check(ctx context.Context, fullMethod string) error {
+	class := methodClass(fullMethod)
+	key := rateLimitKey(ctx)
+	allowed, retryAfter := l.reserve(key, class)
+	if allowed {
+		return nil
+	}
+	return exhaustedError(key, class, retryAfter)
+}
+
+// authenticate wraps authn so a peer whose attempts failed too often is turned away before its credentials are checked
+func (l *rateLimiter) authenticate(authn grpc_auth.AuthFunc) grpc_auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		key := peerKey(ctx)
+		if retryAfter := l.wait(key, limitAuth); retryAfter > 0 {
+			return nil, exhaustedError(key, limitAuth, retryAfter)
+		}
+		newCtx, err := authn(ctx)
+		if status.Code(err) == codes.Unauthenticated {
+			l.reserve(key, limitAuth)
+		}
+		return newCtx, err
+	}
+}
+
+func exhaustedError(key string, class limitClass, retryAfter time.Duration) error {
+	st := status.Newf(codes.ResourceExhausted, "Rate limit for %s requests exceeded, retry in %v", class, retryAfter.Round(time.Millisecond))
+	return withDetails(st,
+		&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(retryAfter)},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     key,
+			Description: string(class) + " requests per second",
+		}}},
+		errorInfo(reasonRateLimited, map[string]string{"limit": string(class)}),
+	)
+}
+
+// rateLimitKey identifies whom a request is billed to: the API key or user behind it, else the peer address
+func rateLimitKey(ctx context.Context) string {
+	if c := callerFromContext(ctx); c != nil {
+		if c.APIKeyID != "" {
+			return "key:" + c.APIKeyID
+		}
+		return "user:" + c.ID
+	}
+	return peerKey(ctx)
+}
+
+// peerKey identifies the client address of a request
+func peerKey(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		// calls through the REST gateway come from loopback, the gateway appends the real client address
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			if fwd := metautils.ExtractIncoming(ctx).Get("x-forwarded-for"); fwd != "" {
+				host = strings.TrimSpace(fwd[strings.LastIndexByte(fwd, ',')+1:])
+			}
+		}
+		return "peer:" + host
+	}
+	return "peer:unknown"
+}
+
+func (l *rateLimiter) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := l.check(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *rateLimiter) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := l.check(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
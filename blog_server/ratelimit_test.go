@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+func peerContext(addr string, md ...string) context.Context {
+	ctx := context.Background()
+	if addr != "" {
+		tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			panic(err)
+		}
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: tcpAddr})
+	}
+	if len(md) > 0 {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(md...))
+	}
+	return ctx
+}
+
+func TestPeerKey(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"remote client", peerContext("203.0.113.7:5000"), "peer:203.0.113.7"},
+		{"forwarded header from a remote client is ignored", peerContext("203.0.113.7:5000", "x-forwarded-for", "198.51.100.1"), "peer:203.0.113.7"},
+		{"gateway on loopback", peerContext("127.0.0.1:5000", "x-forwarded-for", "198.51.100.1"), "peer:198.51.100.1"},
+		{"gateway appends the address it saw last", peerContext("127.0.0.1:5000", "x-forwarded-for", "10.0.0.1, 198.51.100.1"), "peer:198.51.100.1"},
+		{"IPv6 loopback", peerContext("[::1]:5000", "x-forwarded-for", "2001:db8::1"), "peer:2001:db8::1"},
+		{"loopback without header", peerContext("127.0.0.1:5000"), "peer:127.0.0.1"},
+		{"no peer", peerContext(""), "peer:unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := peerKey(tt.ctx); got != tt.want {
+				t.Errorf("peerKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	ctx := peerContext("203.0.113.7:5000")
+	if got := rateLimitKey(ctx); got != "peer:203.0.113.7" {
+		t.Errorf("anonymous rateLimitKey() = %q", got)
+	}
+	if got := rateLimitKey(withCaller(ctx, &caller{ID: "alice"})); got != "user:alice" {
+		t.Errorf("user rateLimitKey() = %q", got)
+	}
+	if got := rateLimitKey(withCaller(ctx, &caller{ID: "alice", APIKeyID: "k1"})); got != "key:k1" {
+		t.Errorf("API key rateLimitKey() = %q", got)
+	}
+}
+
+func TestMethodClass(t *testing.T) {
+	tests := map[string]limitClass{
+		"/blog.BlogService/ReadBlog":      limitRead,
+		"/blog.BlogService/ListBlogs":     limitRead,
+		"/blog.BlogService/ListBlog":      limitStream,
+		"/blog.BlogService/CreateBlog":    limitWrite,
+		"/blog.BlogService/RestoreBlog":   limitRestore,
+		"/apikey.ApiKeyService/ListKeys":  limitRead,
+		"/apikey.ApiKeyService/RevokeKey": limitWrite,
+		"/blog.BlogService/Unknown":       limitWrite,
+	}
+	for method, want := range tests {
+		if got := methodClass(method); got != want {
+			t.Errorf("methodClass(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
+
+// limitOf returns the limit class named by the ErrorInfo of a RESOURCE_EXHAUSTED error
+func limitOf(t *testing.T, err error) limitClass {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("error = %v, want RESOURCE_EXHAUSTED", err)
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return limitClass(info.GetMetadata()["limit"])
+		}
+	}
+	t.Fatalf("error %v has no ErrorInfo", err)
+	return ""
+}
+
+func TestCheckDrawsFromTheClassBucket(t *testing.T) {
+	l := newRateLimiter(map[limitClass]rateLimit{
+		limitRead:    {PerSecond: 0.001, Burst: 1},
+		limitWrite:   {PerSecond: 0.001, Burst: 1},
+		limitRestore: {PerSecond: 0.001, Burst: 1},
+	})
+	alice := withCaller(context.Background(), &caller{ID: "alice"})
+
+	if err := l.check(alice, "/blog.BlogService/CreateBlog"); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	// writes, including unknown methods, share the empty bucket
+	for _, method := range []string{"/blog.BlogService/UpdateBlog", "/blog.BlogService/Unknown"} {
+		if got := limitOf(t, l.check(alice, method)); got != limitWrite {
+			t.Errorf("%s limited by %q, want write", method, got)
+		}
+	}
+	// other classes and other callers have buckets of their own
+	if err := l.check(alice, "/blog.BlogService/ReadBlog"); err != nil {
+		t.Errorf("read after writes: %v", err)
+	}
+	if err := l.check(alice, "/blog.BlogService/RestoreBlog"); err != nil {
+		t.Errorf("restore after writes: %v", err)
+	}
+	if err := l.check(withCaller(context.Background(), &caller{ID: "bob"}), "/blog.BlogService/CreateBlog"); err != nil {
+		t.Errorf("write of another caller: %v", err)
+	}
+	// streams have no limit configured
+	for i := 0; i < 5; i++ {
+		if err := l.check(alice, "/blog.BlogService/ListBlog"); err != nil {
+			t.Fatalf("unlimited stream: %v", err)
+		}
+	}
+}
+
+func TestExhaustedError(t *testing.T) {
+	st := status.Convert(exhaustedError("user:alice", limitWrite, 1500*time.Millisecond))
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("code = %v", st.Code())
+	}
+	var retry *errdetails.RetryInfo
+	var quota *errdetails.QuotaFailure
+	var info *errdetails.ErrorInfo
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.RetryInfo:
+			retry = d
+		case *errdetails.QuotaFailure:
+			quota = d
+		case *errdetails.ErrorInfo:
+			info = d
+		}
+	}
+	if retry == nil || quota == nil || info == nil {
+		t.Fatalf("details = %v, want RetryInfo, QuotaFailure and ErrorInfo", st.Details())
+	}
+	if d, err := ptypes.Duration(retry.GetRetryDelay()); err != nil || d != 1500*time.Millisecond {
+		t.Errorf("retry delay = %v, %v", d, err)
+	}
+	if v := quota.GetViolations(); len(v) != 1 || v[0].GetSubject() != "user:alice" || v[0].GetDescription() != "write requests per second" {
+		t.Errorf("quota violations = %v", v)
+	}
+	if info.GetReason() != reasonRateLimited || info.GetDomain() != errorDomain || info.GetMetadata()["limit"] != "write" {
+		t.Errorf("error info = %v", info)
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	if err := checkLimits(map[limitClass]rateLimit{limitRead: {PerSecond: 20, Burst: 40}, limitStream: {}}); err != nil {
+		t.Errorf("valid limits: %v", err)
+	}
+	if err := checkLimits(map[limitClass]rateLimit{limitWrite: {PerSecond: 2, Burst: 0}}); err == nil {
+		t.Error("a rate without burst was accepted")
+	}
+}
+
+func TestAuthenticateLimitsFailuresPerPeer(t *testing.T) {
+	l := newRateLimiter(map[limitClass]rateLimit{limitAuth: {PerSecond: 0.001, Burst: 2}})
+	calls := 0
+	authn := l.authenticate(func(ctx context.Context) (context.Context, error) {
+		calls++
+		if metadataValue(ctx, "authorization") == "good" {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad")
+	})
+	attacker := func(token string) context.Context {
+		return peerContext("203.0.113.7:5000", "authorization", token)
+	}
+
+	// successes do not count
+	for i := 0; i < 3; i++ {
+		if _, err := authn(attacker("good")); err != nil {
+			t.Fatalf("good credentials: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := authn(attacker("bad")); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("failure %d: %v, want UNAUTHENTICATED", i+1, err)
+		}
+	}
+	calls = 0
+	_, err := authn(attacker("good"))
+	if got := limitOf(t, err); got != limitAuth {
+		t.Errorf("limited by %q, want auth", got)
+	}
+	if calls != 0 {
+		t.Errorf("credentials were checked %d times after the limit was reached", calls)
+	}
+	if _, err := authn(peerContext("198.51.100.1:5000", "authorization", "bad")); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("another peer: %v, want UNAUTHENTICATED", err)
+	}
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
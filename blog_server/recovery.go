@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"runtime/debug"
+)
+
+// recoverPanic turns a panic below the interceptor into an INTERNAL error, logging the stack trace with the request tags
+func recoverPanic(ctx context.Context, logger *logrus.Entry, method string, p interface{}) error {
+	panicsRecovered.WithLabelValues(method).Inc()
+	logger.WithFields(grpc_ctxtags.Extract(ctx).Values()).WithFields(logrus.Fields{
+		"grpc.method": method,
+		"panic":       fmt.Sprint(p),
+		"stack":       string(debug.Stack()),
+	}).Error("Recovered from panic")
+	return internalError(ctx, reasonInternal, fmt.Errorf("panic: %v", p))
+}
+
+func recoveryUnaryInterceptor(logger *logrus.Entry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				resp, err = nil, recoverPanic(ctx, logger, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func recoveryStreamInterceptor(logger *logrus.Entry) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recoverPanic(stream.Context(), logger, info.FullMethod, p)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	"html/template"
+	"strings"
+	"sync"
+)
+
+// markdown renders CommonMark with GitHub extensions, raw HTML is kept and removed by the sanitizer instead
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		// inline styles keep the output self contained, consumers need no stylesheet
+		highlighting.NewHighlighting(
+			highlighting.WithStyle("github"),
+			highlighting.WithFormatOptions(chromahtml.WithClasses(false)),
+		),
+	),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// sanitizer allows what users may write in a blog, plus the styles the highlighter emits
+var sanitizer = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowStyles("color", "background-color", "font-weight", "font-style", "text-decoration").OnElements("pre", "span")
+	return p
+}()
+
+// rendererVersion is part of every cache key, bump it when markdown or sanitizer change what they produce
+const rendererVersion = 1
+
+// renderCacheKey identifies the output of one renderer version for one content
+type renderCacheKey struct {
+	version int
+	sum     [sha256.Size]byte
+}
+
+// renderCache keeps the most recently used rendered HTML, so reads of unchanged blogs skip Markdown and the sanitizer
+type renderCache struct {
+	mu       sync.Mutex
+	capacity int
+	// order holds *renderCacheEntry, the most recently used first
+	order   *list.List
+	entries map[renderCacheKey]*list.Element
+}
+
+type renderCacheEntry struct {
+	key  renderCacheKey
+	html string
+}
+
+// newRenderCache holds up to capacity rendered blogs, 0 disables caching
+func newRenderCache(capacity int) *renderCache {
+	return &renderCache{capacity: capacity, order: list.New(), entries: make(map[renderCacheKey]*list.Element)}
+}
+
+// renderCached is renderContent that reuses the output for content rendered before by the same version
+func (c *renderCache) renderCached(format blogpb.ContentFormat, content string) string {
+	if c.capacity <= 0 {
+		return renderContent(format, content)
+	}
+	h := sha256.New()
+	h.Write([]byte(formatName(format)))
+	h.Write([]byte{0})
+	h.Write([]byte(content))
+	key := renderCacheKey{version: rendererVersion}
+	h.Sum(key.sum[:0])
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		html := e.Value.(*renderCacheEntry).html
+		c.mu.Unlock()
+		return html
+	}
+	c.mu.Unlock()
+
+	// render without the lock, two readers of the same new blog may both render it
+	html := renderContent(format, content)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return html
+	}
+	c.entries[key] = c.order.PushFront(&renderCacheEntry{key: key, html: html})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*renderCacheEntry).key)
+	}
+	return html
+}
+
+// renderContent turns content of the given format into HTML that is safe to embed in a page
+func renderContent(format blogpb.ContentFormat, content string) string {
+	switch format {
+	case blogpb.ContentFormat_MARKDOWN:
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(content), &buf); err != nil {
+			// goldmark only fails on writer errors, which a buffer does not have
+			return renderPlain(content)
+		}
+		return sanitizer.Sanitize(buf.String())
+	case blogpb.ContentFormat_HTML:
+		return sanitizer.Sanitize(content)
+	}
+	return renderPlain(content)
+}
+
+// renderPlain escapes content, blank lines separate paragraphs and other line breaks are kept
+func renderPlain(content string) string {
+	content = strings.Replace(content, "\r\n", "\n", -1)
+	var b strings.Builder
+	for _, p := range strings.Split(content, "\n\n") {
+		p = strings.Trim(p, "\n")
+		if p == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.Replace(template.HTMLEscapeString(p), "\n", "<br>\n", -1))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// contentFormats are the names stored for each format, blogs written before formats existed have none and are plain
+var contentFormats = map[blogpb.ContentFormat]string{
+	blogpb.ContentFormat_PLAIN:    "plain",
+	blogpb.ContentFormat_MARKDOWN: "markdown",
+	blogpb.ContentFormat_HTML:     "html",
+}
+
+func formatName(format blogpb.ContentFormat) string {
+	if name, ok := contentFormats[format]; ok {
+		return name
+	}
+	return contentFormats[blogpb.ContentFormat_PLAIN]
+}
+
+func parseFormatName(name string) blogpb.ContentFormat {
+	for format, n := range contentFormats {
+		if n == name {
+			return format
+		}
+	}
+	return blogpb.ContentFormat_PLAIN
+}
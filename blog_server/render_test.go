@@ -0,0 +1,137 @@
+package main
+
+import (
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"strings"
+	"testing"
+)
+
+func TestRenderContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  blogpb.ContentFormat
+		content string
+		// want must appear in the output and notWant must not, compared in lower case
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "script in HTML",
+			format:  blogpb.ContentFormat_HTML,
+			content: `<p>hi</p><script>alert(1)</script>`,
+			want:    []string{"<p>hi</p>"},
+			notWant: []string{"<script", "alert(1)"},
+		},
+		{
+			name:    "script in Markdown",
+			format:  blogpb.ContentFormat_MARKDOWN,
+			content: "# hi\n\n<script>alert(1)</script>\n",
+			want:    []string{"<h1>hi</h1>"},
+			notWant: []string{"<script", "alert(1)"},
+		},
+		{
+			name:    "javascript link in HTML",
+			format:  blogpb.ContentFormat_HTML,
+			content: `<a href="javascript:alert(1)">x</a>`,
+			notWant: []string{"javascript:", "href"},
+		},
+		{
+			name:    "javascript link in Markdown",
+			format:  blogpb.ContentFormat_MARKDOWN,
+			content: "[x](javascript:alert(1))",
+			notWant: []string{"javascript:", "href"},
+		},
+		{
+			name:    "mixed case javascript link",
+			format:  blogpb.ContentFormat_HTML,
+			content: `<a href=" JaVaScRiPt:alert(1)">x</a>`,
+			notWant: []string{"javascript:", "href"},
+		},
+		{
+			name:    "event handler attributes in HTML",
+			format:  blogpb.ContentFormat_HTML,
+			content: `<img src="https://example.com/a.png" onerror="alert(1)"><p onclick="alert(2)">x</p>`,
+			want:    []string{`src="https://example.com/a.png"`, "<p>x</p>"},
+			notWant: []string{"onerror", "onclick", "alert("},
+		},
+		{
+			name:    "raw HTML inside Markdown",
+			format:  blogpb.ContentFormat_MARKDOWN,
+			content: "text <span onmouseover=\"alert(1)\">hover</span>\n\n<iframe src=\"https://example.com\"></iframe>\n\n<div style=\"position:fixed\">x</div>\n",
+			want:    []string{"hover"},
+			notWant: []string{"onmouseover", "<iframe", "position"},
+		},
+		{
+			name:    "links get nofollow",
+			format:  blogpb.ContentFormat_MARKDOWN,
+			content: "[x](https://example.com)",
+			want:    []string{`href="https://example.com"`, `rel="nofollow"`},
+		},
+		{
+			name:    "highlighter colors are kept",
+			format:  blogpb.ContentFormat_MARKDOWN,
+			content: "```go\nfunc main() {}\n```\n",
+			want:    []string{"<pre", "style=\"color:"},
+			notWant: []string{"<script"},
+		},
+		{
+			name:    "plain text is escaped",
+			format:  blogpb.ContentFormat_PLAIN,
+			content: "<script>alert(1)</script>\n& more\n\nsecond",
+			want:    []string{"<p>&lt;script&gt;alert(1)&lt;/script&gt;<br>\n&amp; more</p>", "<p>second</p>"},
+			notWant: []string{"<script"},
+		},
+		{
+			name:    "unspecified is plain",
+			format:  blogpb.ContentFormat_CONTENT_FORMAT_UNSPECIFIED,
+			content: "<b>x</b>",
+			want:    []string{"&lt;b&gt;x&lt;/b&gt;"},
+			notWant: []string{"<b>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderContent(tt.format, tt.content)
+			lower := strings.ToLower(got)
+			for _, want := range tt.want {
+				if !strings.Contains(lower, strings.ToLower(want)) {
+					t.Errorf("renderContent() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(lower, strings.ToLower(notWant)) {
+					t.Errorf("renderContent() = %q, must not contain %q", got, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	c := newRenderCache(2)
+	markdown := c.renderCached(blogpb.ContentFormat_MARKDOWN, "# a")
+	if want := renderContent(blogpb.ContentFormat_MARKDOWN, "# a"); markdown != want {
+		t.Fatalf("renderCached() = %q, want %q", markdown, want)
+	}
+	// the same content in another format is another entry
+	if plain := c.renderCached(blogpb.ContentFormat_PLAIN, "# a"); plain == markdown {
+		t.Errorf("renderCached() returned the Markdown output for plain text: %q", plain)
+	}
+	c.renderCached(blogpb.ContentFormat_MARKDOWN, "# a")
+	c.renderCached(blogpb.ContentFormat_HTML, "b")
+	if c.order.Len() != 2 || len(c.entries) != 2 {
+		t.Fatalf("cache holds %d entries and %d keys, want 2", c.order.Len(), len(c.entries))
+	}
+	// the plain entry was used least recently and is evicted first
+	for _, e := range c.entries {
+		if e.Value.(*renderCacheEntry).html == renderContent(blogpb.ContentFormat_PLAIN, "# a") {
+			t.Errorf("least recently used entry was kept")
+		}
+	}
+
+	disabled := newRenderCache(0)
+	disabled.renderCached(blogpb.ContentFormat_MARKDOWN, "# a")
+	if len(disabled.entries) != 0 {
+		t.Errorf("disabled cache holds %d entries", len(disabled.entries))
+	}
+}
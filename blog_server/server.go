@@ -2,76 +2,121 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"flag"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus/ctxlogrus"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/k-yomo/blog_with_grpc/apikeypb"
 	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"github.com/k-yomo/blog_with_grpc/tracing"
 	"github.com/mongodb/mongo-go-driver/bson/primitive"
 	"github.com/mongodb/mongo-go-driver/mongo"
-	"github.com/mongodb/mongo-go-driver/x/bsonx"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
-	"google.golang.org/grpc/status"
-	"gopkg.in/mgo.v2/bson"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 )
 
-var collection *mongo.Collection
-
-type server struct{}
-
-type blogItem struct {
-	ID       primitive.ObjectID `bson:"_id, omitempty"`
-	AuthorID string             `bson:"author_id"`
-	Content  string             `bson:"content"`
-	Title    string             `bson:"title"`
+type server struct {
+	store       blogStore
+	idempotency *idempotencyStore
+	// listBatchSize is the number of blogs ListBlog fetches from MongoDB per round trip
+	listBatchSize int32
+	// listMaxMessages caps the blogs sent on one ListBlog stream, 0 means no cap
+	listMaxMessages int
 }
 
-func (*server) CreateBlog(ctx context.Context, req *blogpb.CreateBlogRequest) (*blogpb.CreateBlogResponse, error) {
-	fmt.Println("Create blog request")
+func (s *server) CreateBlog(ctx context.Context, req *blogpb.CreateBlogRequest) (*blogpb.CreateBlogResponse, error) {
 	blog := req.GetBlog()
-	data := blogItem{
-		ID:       primitive.NewObjectID(),
-		AuthorID: blog.GetAuthorId(),
-		Title:    blog.GetTitle(),
-		Content:  blog.GetContent(),
+	authorID := blog.GetAuthorId()
+	if authorID == "" {
+		if c := callerFromContext(ctx); c != nil {
+			authorID = c.ID
+		}
+	}
+	if err := authorizeAuthor(ctx, authorID); err != nil {
+		return nil, err
 	}
 
-	res, err := collection.InsertOne(context.Background(), data)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, fmt.Sprintf("Internal error: %v", err))
+	data := &blogItem{
+		ID:            primitive.NewObjectID(),
+		AuthorID:      authorID,
+		Title:         blog.GetTitle(),
+		Content:       blog.GetContent(),
+		ContentFormat: formatName(blog.GetContentFormat()),
 	}
-	oid, ok := res.InsertedID.(primitive.ObjectID)
-	if !ok {
-		return nil, status.Errorf(codes.Internal, fmt.Sprintf("Cannot convert to OID"))
+
+	var claim *idempotencyRecord
+	if key := idempotencyKey(ctx, req); key != "" {
+		claim = &idempotencyRecord{
+			ID:          callerFromContext(ctx).ID + ":" + key,
+			RequestHash: hashBlogRequest(data),
+			BlogID:      data.ID,
+			CreatedAt:   time.Now().UTC(),
+		}
+		existing, err := s.idempotency.claim(ctx, claim)
+		if err != nil {
+			return nil, storageError(ctx, err, resourceBlog, data.ID.Hex())
+		}
+		if existing != nil {
+			if err := existing.replayError(claim.RequestHash); err != nil {
+				return nil, err
+			}
+			// the payload is identical, so the original response is rebuilt from the request
+			ctxlogrus.AddFields(ctx, logrus.Fields{"blog_id": existing.BlogID.Hex(), "idempotent_replay": true})
+			data.ID = existing.BlogID
+			return &blogpb.CreateBlogResponse{Blog: data.toBlogPb()}, nil
+		}
+		// taking over an abandoned claim keeps its blog ID
+		data.ID = claim.BlogID
 	}
 
-	return &blogpb.CreateBlogResponse{
-		Blog: &blogpb.Blog{
-			Id:       oid.Hex(),
-			AuthorId: blog.AuthorId,
-			Title:    blog.Title,
-			Content:  blog.Content,
-		},
-	}, nil
+	if err := s.store.insert(ctx, data); err != nil {
+		if claim != nil {
+			if err := s.idempotency.release(ctx, claim.ID); err != nil {
+				// retries with the key are answered with ABORTED until the lease runs out
+				ctxlogrus.Extract(ctx).WithError(err).Warn("Cannot release idempotency record")
+			}
+		}
+		return nil, storageError(ctx, err, resourceBlog, data.ID.Hex())
+	}
+	ctxlogrus.AddFields(ctx, logrus.Fields{"blog_id": data.ID.Hex()})
+	if claim != nil {
+		if err := s.idempotency.complete(ctx, claim.ID); err != nil {
+			// the blog exists, a replay is answered with ABORTED until the lease runs out and the blog is found
+			ctxlogrus.Extract(ctx).WithError(err).Warn("Cannot complete idempotency record")
+		}
+	}
+
+	return &blogpb.CreateBlogResponse{Blog: data.toBlogPb()}, nil
 }
 
-func (*server) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (*blogpb.ReadBlogResponse, error) {
-	fmt.Println("Read blog request")
+func (s *server) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (*blogpb.ReadBlogResponse, error) {
 	blogID := req.GetBlogId()
+	ctxlogrus.AddFields(ctx, logrus.Fields{"blog_id": blogID})
+	if err := authorizeRead(ctx); err != nil {
+		return nil, err
+	}
 	oid, err := primitive.ObjectIDFromHex(blogID)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("Cannot parse ID: %v", err))
+		return nil, invalidIDError("blog_id", blogID)
 	}
 
-	blogItem := &blogItem{}
-	filter := bson.M{"_id": oid}
-
-	res := collection.FindOne(context.Background(), filter)
-	if err := res.Decode(blogItem); err != nil {
-		return nil, status.Errorf(codes.NotFound, fmt.Sprintf("Cannot find blog with specified ID: %v", err))
+	blogItem, err := s.store.findByID(ctx, oid)
+	if err != nil {
+		return nil, storageError(ctx, err, resourceBlog, blogID)
 	}
 
 	return &blogpb.ReadBlogResponse{
@@ -79,129 +124,421 @@ func (*server) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (*blog
 	}, nil
 }
 
-func (*server) UpdateBlog(ctx context.Context, req *blogpb.UpdateBlogRequest) (*blogpb.UpdateBlogResponse, error) {
-	fmt.Println("Update blog request")
+func (s *server) UpdateBlog(ctx context.Context, req *blogpb.UpdateBlogRequest) (*blogpb.UpdateBlogResponse, error) {
 	blog := req.GetBlog()
+	ctxlogrus.AddFields(ctx, logrus.Fields{"blog_id": blog.GetId()})
 	oid, err := primitive.ObjectIDFromHex(blog.GetId())
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("Cannnot parse ID"))
+		return nil, invalidIDError("blog.id", blog.GetId())
 	}
 
-	data := &blogItem{}
-	filter := bson.M{"_id": oid}
-
-	res := collection.FindOne(context.Background(), filter)
-	if err := res.Decode(data); err != nil {
-		return nil, status.Errorf(codes.NotFound, fmt.Sprintf("Cannot find blog with specified ID: %v", err))
+	data, err := s.store.findByID(ctx, oid)
+	if err != nil {
+		return nil, storageError(ctx, err, resourceBlog, blog.GetId())
+	}
+	if err := authorizeAuthor(ctx, data.AuthorID); err != nil {
+		return nil, err
 	}
 
-	data.AuthorID = blog.GetAuthorId()
-	data.Title = blog.GetTitle()
-	data.Content = blog.GetContent()
-	_, err = collection.ReplaceOne(context.Background(), filter, data)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, fmt.Sprintf("Cannot update object in MongoDB: %v", err))
+	fields := maskedFields(req.GetUpdateMask())
+	updates := func(field string) bool { return fields == nil || fields[field] }
+
+	// only admins may hand a blog over to another author
+	if authorID := blog.GetAuthorId(); updates("author_id") && authorID != "" && authorID != data.AuthorID {
+		if !callerFromContext(ctx).isAdmin() {
+			return nil, permissionDeniedError(reasonAdminRequired, nil, "Only admins can change the author of a blog")
+		}
+		if violation := blogAuthorIDRule.check("blog.", authorID); violation != nil {
+			return nil, invalidArgument([]*errdetails.BadRequest_FieldViolation{violation})
+		}
+		data.AuthorID = authorID
+	}
+	if updates("title") {
+		data.Title = blog.GetTitle()
+	}
+	if updates("content") {
+		data.Content = blog.GetContent()
+	}
+	// clients that do not know about formats send none, which must not turn Markdown into plain text
+	if format := blog.GetContentFormat(); updates("content_format") && format != blogpb.ContentFormat_CONTENT_FORMAT_UNSPECIFIED {
+		data.ContentFormat = formatName(format)
+	}
+	if err := s.store.replace(ctx, data); err != nil {
+		return nil, storageError(ctx, err, resourceBlog, blog.GetId())
 	}
 	return &blogpb.UpdateBlogResponse{Blog: data.toBlogPb()}, nil
 }
 
-func (*server) DeleteBlog(ctx context.Context, req *blogpb.DeleteBlogRequest) (*blogpb.DeleteBlogResponse, error) {
-	fmt.Println("Update blog request")
+func (s *server) DeleteBlog(ctx context.Context, req *blogpb.DeleteBlogRequest) (*blogpb.DeleteBlogResponse, error) {
+	ctxlogrus.AddFields(ctx, logrus.Fields{"blog_id": req.GetBlogId()})
 	oid, err := primitive.ObjectIDFromHex(req.GetBlogId())
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("Cannnot parse ID"))
+		return nil, invalidIDError("blog_id", req.GetBlogId())
 	}
 
-	filter := bson.M{"_id": oid}
-	res, err := collection.DeleteOne(context.Background(), filter)
+	data, err := s.store.findByID(ctx, oid)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, fmt.Sprintf("Cannot delete object in MongoDB: %v", err))
+		return nil, storageError(ctx, err, resourceBlog, req.GetBlogId())
+	}
+	if err := authorizeAuthor(ctx, data.AuthorID); err != nil {
+		return nil, err
 	}
 
-	if res.DeletedCount == 0 {
-		return nil, status.Errorf(codes.NotFound, fmt.Sprint("Cannot find blog in MongoDB"))
+	if err := s.store.deleteByID(ctx, oid); err != nil {
+		return nil, storageError(ctx, err, resourceBlog, req.GetBlogId())
 	}
 
 	return &blogpb.DeleteBlogResponse{BlogId: req.GetBlogId()}, nil
 }
 
-func (*server) ListBlog(req *blogpb.ListBlogRequest, stream blogpb.BlogService_ListBlogServer) error {
-	fmt.Println("List blog request")
-	cursor, err := collection.Find(context.Background(), bsonx.Doc{})
+func (s *server) ListBlog(req *blogpb.ListBlogRequest, stream blogpb.BlogService_ListBlogServer) error {
+	ctx := stream.Context()
+	if err := authorizeRead(ctx); err != nil {
+		return err
+	}
+	sent := 0
+	truncated := false
+	// one more blog than allowed is fetched to tell whether the listing was cut short
+	limit := int64(0)
+	if s.listMaxMessages > 0 {
+		limit = int64(s.listMaxMessages) + 1
+	}
+	var sendErr error
+	err := s.store.each(ctx, s.listBatchSize, limit, func(blogItem *blogItem) error {
+		if s.listMaxMessages > 0 && sent >= s.listMaxMessages {
+			truncated = true
+			return errStopIteration
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Send blocks while the client is not reading, stopping the iteration closes the cursor
+		if err := stream.Send(&blogpb.ListBlogResponse{Blog: blogItem.toBlogPb()}); err != nil {
+			sendErr = err
+			return errStopIteration
+		}
+		sent++
+		return nil
+	})
+	ctxlogrus.AddFields(ctx, logrus.Fields{"blogs_sent": sent, "truncated": truncated})
+	if sendErr != nil {
+		return sendErr
+	}
 	if err != nil {
-		return status.Errorf(codes.Internal, fmt.Sprintf("unknown internal error: %v", err))
+		return storageError(ctx, err, resourceBlog, "")
 	}
-	defer cursor.Close(context.Background())
+	if truncated {
+		stream.SetTrailer(metadata.Pairs(listTruncatedTrailer, "true"))
+	}
+
+	return nil
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
 
-	for cursor.Next(context.Background()) {
-		blogItem := &blogItem{}
-		err := cursor.Decode(blogItem)
+func (s *server) ListBlogs(ctx context.Context, req *blogpb.ListBlogsRequest) (*blogpb.ListBlogsResponse, error) {
+	if err := authorizeRead(ctx); err != nil {
+		return nil, err
+	}
+	pageSize := int64(req.GetPageSize())
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	// the page token is the ID of the last blog of the previous page
+	var after *primitive.ObjectID
+	if token := req.GetPageToken(); token != "" {
+		oid, err := primitive.ObjectIDFromHex(token)
 		if err != nil {
-			return status.Errorf(codes.Internal, fmt.Sprintf("Error while decoding data from MongoDB: %v", err))
+			return nil, invalidIDError("page_token", token)
 		}
-		stream.Send(&blogpb.ListBlogResponse{Blog: blogItem.toBlogPb()})
+		after = &oid
+	}
+
+	// one more blog than requested tells whether there is a next page
+	items, err := s.store.page(ctx, after, pageSize+1)
+	if err != nil {
+		return nil, storageError(ctx, err, resourceBlog, "")
 	}
-	if err := cursor.Err(); err != nil {
-		return status.Errorf(codes.Internal, fmt.Sprintf("Unknown internal error: %v", err))
+	res := &blogpb.ListBlogsResponse{}
+	if int64(len(items)) > pageSize {
+		items = items[:pageSize]
+		res.NextPageToken = items[len(items)-1].ID.Hex()
 	}
+	for _, item := range items {
+		res.Blogs = append(res.Blogs, item.toBlogPb())
+	}
+	ctxlogrus.AddFields(ctx, logrus.Fields{"blogs_sent": len(res.Blogs)})
+	return res, nil
+}
 
-	return nil
+func (s *server) RestoreBlog(ctx context.Context, req *blogpb.RestoreBlogRequest) (*blogpb.RestoreBlogResponse, error) {
+	blog := req.GetBlog()
+	ctxlogrus.AddFields(ctx, logrus.Fields{"blog_id": blog.GetId()})
+	c := callerFromContext(ctx)
+	if c == nil {
+		return nil, unauthenticatedError(reasonAuthenticationRequired, "Authentication required")
+	}
+	// a restore can overwrite any blog, so it is not enough to own the author
+	if !c.isAdmin() {
+		return nil, permissionDeniedError(reasonAdminRequired, nil, "Only admins can restore blogs")
+	}
+	oid, err := primitive.ObjectIDFromHex(blog.GetId())
+	if err != nil {
+		return nil, invalidIDError("blog.id", blog.GetId())
+	}
+
+	data := &blogItem{
+		ID:            oid,
+		AuthorID:      blog.GetAuthorId(),
+		Title:         blog.GetTitle(),
+		Content:       blog.GetContent(),
+		ContentFormat: formatName(blog.GetContentFormat()),
+	}
+	created, err := s.store.upsert(ctx, data)
+	if err != nil {
+		return nil, storageError(ctx, err, resourceBlog, blog.GetId())
+	}
+	ctxlogrus.AddFields(ctx, logrus.Fields{"created": created})
+	return &blogpb.RestoreBlogResponse{Blog: data.toBlogPb(), Created: created}, nil
 }
 
+// listTruncatedTrailer is set when ListBlog stopped at the per stream message cap
+const listTruncatedTrailer = "x-list-truncated"
+
+// blogRenderer caches rendered content for toBlogPb, main sizes it with -render-cache-size
+var blogRenderer = newRenderCache(1000)
+
+// toBlogPb renders the content on read through blogRenderer, so a stricter sanitizer applies to blogs written before it
 func (b *blogItem) toBlogPb() *blogpb.Blog {
+	format := parseFormatName(b.ContentFormat)
 	return &blogpb.Blog{
-		Id:       b.ID.Hex(),
-		AuthorId: b.AuthorID,
-		Title:    b.Title,
-		Content:  b.Content,
+		Id:            b.ID.Hex(),
+		AuthorId:      b.AuthorID,
+		Title:         b.Title,
+		Content:       b.Content,
+		ContentFormat: format,
+		RenderedHtml:  blogRenderer.renderCached(format, b.Content),
 	}
 }
 
 func main() {
-	// if we crash the go code, we get the file name and line number
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	jwtSecret := flag.String("jwt-secret", os.Getenv("BLOG_JWT_SECRET"), "HMAC secret used to verify bearer tokens")
+	readRate := flag.Float64("read-rate", 20, "reads per second allowed per caller, 0 disables the limit")
+	readBurst := flag.Int("read-burst", 40, "burst size of the per caller read limit")
+	writeRate := flag.Float64("write-rate", 2, "writes per second allowed per caller, 0 disables the limit")
+	writeBurst := flag.Int("write-burst", 5, "burst size of the per caller write limit")
+	streamRate := flag.Float64("stream-rate", 0.5, "ListBlog streams per second allowed per caller, 0 disables the limit")
+	streamBurst := flag.Int("stream-burst", 3, "burst size of the per caller ListBlog limit")
+	restoreRate := flag.Float64("restore-rate", 100, "RestoreBlog calls per second allowed per caller, 0 disables the limit")
+	restoreBurst := flag.Int("restore-burst", 200, "burst size of the per caller RestoreBlog limit")
+	authFailureRate := flag.Float64("auth-failure-rate", 0.2, "failed authentications per second allowed per client address, 0 disables the limit")
+	authFailureBurst := flag.Int("auth-failure-burst", 10, "burst size of the per address failed authentication limit")
+	metricsAddr := flag.String("metrics-addr", "0.0.0.0:9090", "address the Prometheus /metrics endpoint listens on")
+	logLevel := flag.String("log-level", "info", "minimum level of log entries: debug, info, warn or error")
+	rpcTimeout := flag.Duration("rpc-timeout", 10*time.Second, "deadline of unary RPCs whose client did not set one, 0 disables it")
+	streamTimeout := flag.Duration("stream-timeout", 5*time.Minute, "maximum duration of ListBlog streams, also when the client set a later deadline, 0 disables it")
+	listBatchSize := flag.Int("list-batch-size", 100, "number of blogs ListBlog fetches from MongoDB per round trip")
+	listMaxMessages := flag.Int("list-max-messages", 10000, "maximum number of blogs sent on one ListBlog stream, 0 disables the cap")
+	idempotencyTTL := flag.Duration("idempotency-ttl", 24*time.Hour, "how long CreateBlog idempotency keys are remembered")
+	idempotencyLease := flag.Duration("idempotency-lease", time.Minute, "how long a CreateBlog in progress blocks retries with its key before it is assumed to have failed")
+	renderCacheSize := flag.Int("render-cache-size", 1000, "number of rendered blogs kept in memory, 0 renders on every read")
+	gatewayAddr := flag.String("gateway-addr", "0.0.0.0:8080", "address the REST/JSON gateway listens on, empty disables it")
+	grpcWebAddr := flag.String("grpc-web-addr", "0.0.0.0:8081", "address gRPC-Web for browsers listens on, empty disables it")
+	corsOrigins := flag.String("cors-origins", os.Getenv("BLOG_CORS_ORIGINS"), "comma separated origins allowed to call gRPC-Web, * allows any")
+	storage := flag.String("storage", "mongodb://localhost:27017/blog_with_grpc", "where blogs are stored, mongodb://host:port/database or snapshot:path")
+	snapshotInterval := flag.Duration("snapshot-interval", 5*time.Second, "how often changes to snapshot: storage are saved to disk")
+	traceExporter := flag.String("trace-exporter", os.Getenv("BLOG_TRACE_EXPORTER"), "where spans are exported: none, stdout or otlp")
+	flag.Parse()
+
+	logger, err := newLogger(*logLevel)
+	if err != nil {
+		log.Fatalf("Invalid log level: %v", err)
+	}
+
+	if *jwtSecret == "" {
+		logger.Fatal("JWT secret is required, set -jwt-secret or BLOG_JWT_SECRET")
+	}
+
+	blogRenderer = newRenderCache(*renderCacheSize)
 
-	fmt.Println("Connecting to MongoDB")
+	shutdownTracing, err := tracing.Setup(context.Background(), *traceExporter, "blog_server")
+	if err != nil {
+		logger.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	logger.Info("Connecting to MongoDB")
 	client, err := mongo.NewClient("mongodb://localhost:27017")
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
-	err = client.Connect(context.TODO())
+	connectCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = client.Connect(connectCtx)
+	cancel()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// API keys and idempotency keys stay in MongoDB whatever backend holds the blogs
+	logger.Infof("Opening blog storage %s", *storage)
+	store, closeStore, err := openStore(context.Background(), *storage)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatalf("Failed to open blog storage: %v", err)
+	}
+	saveCtx, stopSaving := context.WithCancel(context.Background())
+	if snapshot, ok := store.(*snapshotStore); ok {
+		if *snapshotInterval <= 0 {
+			logger.Fatal("-snapshot-interval must be positive")
+		}
+		go snapshot.saveEvery(saveCtx, *snapshotInterval, func(err error) {
+			logger.Errorf("Failed to save snapshot: %v", err)
+		})
 	}
 
-	fmt.Println("Blog Service Started")
-	collection = client.Database("blog_with_grpc").Collection("blog")
+	logger.Info("Blog Service Started")
+	db := client.Database("blog_with_grpc")
+	keys := &apiKeyServer{keys: db.Collection(apiKeyCollection)}
+	idempotencyRecords := &mongoIdempotencyRecords{coll: db.Collection(idempotencyCollection)}
+	idempotency := &idempotencyStore{records: idempotencyRecords, ttl: *idempotencyTTL, lease: *idempotencyLease, blogs: store}
+	if err := idempotencyRecords.ensureIndexes(context.Background(), *idempotencyTTL); err != nil {
+		// an existing index with another expiry is kept, records are still checked against the ttl
+		logger.Warnf("Cannot create idempotency key index: %v", err)
+	}
 
 	lis, err := net.Listen("tcp", "0.0.0.0:50051")
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		logger.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
-	blogpb.RegisterBlogServiceServer(s, &server{})
+	auth := &authenticator{secret: []byte(*jwtSecret), keys: keys}
+	limits := map[limitClass]rateLimit{
+		limitRead:    {PerSecond: *readRate, Burst: *readBurst},
+		limitWrite:   {PerSecond: *writeRate, Burst: *writeBurst},
+		limitStream:  {PerSecond: *streamRate, Burst: *streamBurst},
+		limitRestore: {PerSecond: *restoreRate, Burst: *restoreBurst},
+		limitAuth:    {PerSecond: *authFailureRate, Burst: *authFailureBurst},
+	}
+	if err := checkLimits(limits); err != nil {
+		logger.Fatalf("Invalid rate limit: %v", err)
+	}
+	limiter := newRateLimiter(limits)
+	logEntry := logrus.NewEntry(logger)
+	s := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc_middleware.WithUnaryServerChain(
+			grpc_ctxtags.UnaryServerInterceptor(),
+			requestIDUnaryInterceptor,
+			// catches panics of the interceptors below, including metrics and logging themselves
+			recoveryUnaryInterceptor(logEntry),
+			deadlineUnaryInterceptor(*rpcTimeout),
+			metricsUnaryInterceptor,
+			grpc_logrus.UnaryServerInterceptor(logEntry),
+			// catches panics of auth, rate limiting, validation and handlers, so they are logged and counted as INTERNAL errors
+			recoveryUnaryInterceptor(logEntry),
+			contextErrorUnaryInterceptor,
+			grpc_auth.UnaryServerInterceptor(limiter.authenticate(auth.authenticate)),
+			limiter.unaryInterceptor,
+			validationUnaryInterceptor,
+		),
+		grpc_middleware.WithStreamServerChain(
+			grpc_ctxtags.StreamServerInterceptor(),
+			requestIDStreamInterceptor,
+			recoveryStreamInterceptor(logEntry),
+			deadlineStreamInterceptor(*streamTimeout),
+			metricsStreamInterceptor,
+			grpc_logrus.StreamServerInterceptor(logEntry),
+			recoveryStreamInterceptor(logEntry),
+			contextErrorStreamInterceptor,
+			grpc_auth.StreamServerInterceptor(limiter.authenticate(auth.authenticate)),
+			limiter.streamInterceptor,
+		),
+	)
+	blogpb.RegisterBlogServiceServer(s, &server{store: store, idempotency: idempotency, listBatchSize: int32(*listBatchSize), listMaxMessages: *listMaxMessages})
+	apikeypb.RegisterApiKeyServiceServer(s, keys)
 	// Register reflection service on gRPC server
 	reflection.Register(s)
 
 	go func() {
-		fmt.Println("Starting Server...")
+		logger.Info("Starting Server...")
 		if err := s.Serve(lis); err != nil {
-			log.Fatalf("Failed to serve: %v", err)
+			logger.Fatalf("Failed to serve: %v", err)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	httpServer := &http.Server{Addr: *metricsAddr, Handler: mux}
+	go func() {
+		logger.Infof("Serving metrics on %s", *metricsAddr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("Failed to serve metrics: %v", err)
 		}
 	}()
 
-	// Wait for Control C to exit
-	ch := make(chan os.Signal)
-	signal.Notify(ch, os.Interrupt)
+	var gatewayServer *http.Server
+	if *gatewayAddr != "" {
+		_, port, _ := net.SplitHostPort(lis.Addr().String())
+		gateway, err := newGateway(context.Background(), net.JoinHostPort("localhost", port))
+		if err != nil {
+			logger.Fatalf("Failed to set up gateway: %v", err)
+		}
+		gatewayServer = &http.Server{Addr: *gatewayAddr, Handler: withDocs(gateway)}
+		go func() {
+			logger.Infof("Serving REST gateway on %s", *gatewayAddr)
+			if err := gatewayServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatalf("Failed to serve gateway: %v", err)
+			}
+		}()
+	}
+
+	var grpcWebServer *http.Server
+	if *grpcWebAddr != "" {
+		grpcWebServer = &http.Server{Addr: *grpcWebAddr, Handler: newGrpcWebHandler(s, parseOrigins(*corsOrigins))}
+		go func() {
+			logger.Infof("Serving gRPC-Web on %s", *grpcWebAddr)
+			if err := grpcWebServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatalf("Failed to serve gRPC-Web: %v", err)
+			}
+		}()
+	}
+
+	// Wait for Control C, or the SIGTERM of docker stop, systemd and Kubernetes, to exit
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
 
 	// Block until a signal is received
 	<-ch
-	fmt.Println("Stopping  the server")
+	logger.Info("Stopping  the server")
+	if gatewayServer != nil {
+		gatewayServer.Close()
+	}
+	if grpcWebServer != nil {
+		grpcWebServer.Close()
+	}
 	s.Stop()
-	fmt.Println("Closing  the listener")
+	httpServer.Close()
+	logger.Info("Closing  the listener")
 	lis.Close()
-	fmt.Println("Closing MongoDB Connection")
+	logger.Info("Closing blog storage")
+	stopSaving()
+	if err := closeStore(context.Background()); err != nil {
+		logger.Errorf("Failed to close blog storage: %v", err)
+	}
+	logger.Info("Closing MongoDB Connection")
 	client.Disconnect(context.TODO())
-	fmt.Println("End of Program")
+	logger.Info("Flushing traces")
+	shutdownTracing(context.Background())
+	logger.Info("End of Program")
 }
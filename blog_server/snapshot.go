@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// snapshotStore keeps the blogs in memory and writes them to a JSON Lines file on save.
+// It holds copies made by migrate, and suits small data sets and tests.
+type snapshotStore struct {
+	path  string
+	mu    sync.RWMutex
+	items map[primitive.ObjectID]*blogItem
+	// ids are the keys of items in ascending order
+	ids   []primitive.ObjectID
+	dirty bool
+}
+
+// snapshotLine is one blog in a snapshot file
+type snapshotLine struct {
+	ID            string `json:"id"`
+	AuthorID      string `json:"author_id"`
+	Title         string `json:"title"`
+	Content       string `json:"content"`
+	ContentFormat string `json:"content_format,omitempty"`
+}
+
+func idLess(a, b primitive.ObjectID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// openSnapshot loads the snapshot at path, a missing file is an empty snapshot
+func openSnapshot(path string) (*snapshotStore, error) {
+	s := &snapshotStore{path: path, items: map[primitive.ObjectID]*blogItem{}}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for n := 1; scanner.Scan(); n++ {
+		var line snapshotLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		id, err := primitive.ObjectIDFromHex(line.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid id %q", path, n, line.ID)
+		}
+		s.items[id] = &blogItem{ID: id, AuthorID: line.AuthorID, Title: line.Title, Content: line.Content, ContentFormat: line.ContentFormat}
+		s.ids = append(s.ids, id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(s.ids, func(i, j int) bool { return idLess(s.ids[i], s.ids[j]) })
+	return s, nil
+}
+
+// save writes the blogs to a temporary file that replaces the snapshot, so a crash never leaves half a file
+func (s *snapshotStore) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, id := range s.ids {
+		item := s.items[id]
+		if err := enc.Encode(&snapshotLine{ID: id.Hex(), AuthorID: item.AuthorID, Title: item.Title, Content: item.Content, ContentFormat: item.ContentFormat}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// saveEvery saves changes every interval until ctx ends, so a crash loses at most the writes of one interval
+func (s *snapshotStore) saveEvery(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.save(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// search returns the index of the first ID not less than id
+func (s *snapshotStore) search(id primitive.ObjectID) int {
+	return sort.Search(len(s.ids), func(i int) bool { return !idLess(s.ids[i], id) })
+}
+
+// put stores a copy of item, so later changes by the caller do not leak in
+func (s *snapshotStore) put(item *blogItem) (created bool) {
+	copied := *item
+	_, exists := s.items[item.ID]
+	s.items[item.ID] = &copied
+	s.dirty = true
+	if exists {
+		return false
+	}
+	i := s.search(item.ID)
+	s.ids = append(s.ids, primitive.ObjectID{})
+	copy(s.ids[i+1:], s.ids[i:])
+	s.ids[i] = item.ID
+	return true
+}
+
+func (s *snapshotStore) insert(ctx context.Context, item *blogItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[item.ID]; ok {
+		return fmt.Errorf("duplicate id %s", item.ID.Hex())
+	}
+	s.put(item)
+	return nil
+}
+
+func (s *snapshotStore) findByID(ctx context.Context, id primitive.ObjectID) (*blogItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[id]
+	if !ok {
+		return nil, errNotFound
+	}
+	copied := *item
+	return &copied, nil
+}
+
+func (s *snapshotStore) replace(ctx context.Context, item *blogItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[item.ID]; !ok {
+		return errNotFound
+	}
+	s.put(item)
+	return nil
+}
+
+func (s *snapshotStore) upsert(ctx context.Context, item *blogItem) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(item), nil
+}
+
+func (s *snapshotStore) deleteByID(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[id]; !ok {
+		return errNotFound
+	}
+	delete(s.items, id)
+	i := s.search(id)
+	s.ids = append(s.ids[:i], s.ids[i+1:]...)
+	s.dirty = true
+	return nil
+}
+
+func (s *snapshotStore) page(ctx context.Context, after *primitive.ObjectID, limit int64) ([]*blogItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := 0
+	if after != nil {
+		i = s.search(*after)
+		if i < len(s.ids) && s.ids[i] == *after {
+			i++
+		}
+	}
+	var items []*blogItem
+	for ; i < len(s.ids) && (limit <= 0 || int64(len(items)) < limit); i++ {
+		copied := *s.items[s.ids[i]]
+		items = append(items, &copied)
+	}
+	return items, nil
+}
+
+func (s *snapshotStore) each(ctx context.Context, batchSize int32, limit int64, fn func(*blogItem) error) error {
+	items, err := s.page(ctx, nil, limit)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(item); err == errStopIteration {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
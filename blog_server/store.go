@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/mongodb/mongo-go-driver/x/bsonx"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+type blogItem struct {
+	ID       primitive.ObjectID `bson:"_id, omitempty"`
+	AuthorID string             `bson:"author_id"`
+	Content  string             `bson:"content"`
+	Title    string             `bson:"title"`
+	// ContentFormat is a name from contentFormats
+	ContentFormat string `bson:"content_format,omitempty"`
+}
+
+// errNotFound is returned when no document matches the given ID
+var errNotFound = errors.New("not found")
+
+// isNotFound tells a lookup that matched nothing, which is not a failure of the storage
+func isNotFound(err error) bool {
+	return err == errNotFound || err == mongo.ErrNoDocuments
+}
+
+// errStopIteration is returned by the func passed to each to stop early without an error
+var errStopIteration = errors.New("stop iteration")
+
+// blogStore is a storage backend for blogs, items keep the ID they are given
+type blogStore interface {
+	insert(ctx context.Context, item *blogItem) error
+	// findByID, replace and deleteByID return errNotFound for an unknown ID
+	findByID(ctx context.Context, id primitive.ObjectID) (*blogItem, error)
+	replace(ctx context.Context, item *blogItem) error
+	upsert(ctx context.Context, item *blogItem) (created bool, err error)
+	deleteByID(ctx context.Context, id primitive.ObjectID) error
+	page(ctx context.Context, after *primitive.ObjectID, limit int64) ([]*blogItem, error)
+	each(ctx context.Context, batchSize int32, limit int64, fn func(*blogItem) error) error
+}
+
+// mongoStore wraps the blog collection, tracing and recording the latency of every operation
+type mongoStore struct {
+	coll *mongo.Collection
+}
+
+const (
+	blogCollection        = "blog"
+	apiKeyCollection      = "api_keys"
+	idempotencyCollection = "idempotency_keys"
+)
+
+// startOp starts a child span for a storage operation, the returned func ends it with the operation's error
+func startOp(ctx context.Context, collection, op string) (context.Context, func(*error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "mongodb."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "mongodb"),
+			attribute.String("db.operation", op),
+			attribute.String("db.mongodb.collection", collection),
+		),
+	)
+	return ctx, func(err *error) {
+		observeStorage(collection, op, time.Since(start), *err)
+		if *err != nil && !isNotFound(*err) {
+			span.RecordError(*err)
+			span.SetStatus(otelcodes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
+
+func (s *mongoStore) insert(ctx context.Context, item *blogItem) (err error) {
+	ctx, done := startOp(ctx, blogCollection, "insert")
+	defer done(&err)
+	_, err = s.coll.InsertOne(ctx, item)
+	return err
+}
+
+func (s *mongoStore) findByID(ctx context.Context, id primitive.ObjectID) (item *blogItem, err error) {
+	ctx, done := startOp(ctx, blogCollection, "find_one")
+	defer done(&err)
+	item = &blogItem{}
+	if err := s.coll.FindOne(ctx, bson.M{"_id": id}).Decode(item); err == mongo.ErrNoDocuments {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *mongoStore) replace(ctx context.Context, item *blogItem) (err error) {
+	ctx, done := startOp(ctx, blogCollection, "replace")
+	defer done(&err)
+	res, err := s.coll.ReplaceOne(ctx, bson.M{"_id": item.ID}, item)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+// upsert writes item under its ID whether or not it exists, created tells which happened
+func (s *mongoStore) upsert(ctx context.Context, item *blogItem) (created bool, err error) {
+	ctx, done := startOp(ctx, blogCollection, "upsert")
+	defer done(&err)
+	res, err := s.coll.ReplaceOne(ctx, bson.M{"_id": item.ID}, item, options.Replace().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return res.UpsertedID != nil, nil
+}
+
+func (s *mongoStore) deleteByID(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, done := startOp(ctx, blogCollection, "delete")
+	defer done(&err)
+	res, err := s.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+// page returns up to limit blogs with an ID greater than after, or from the start if after is nil,
+// in ID and so creation order
+func (s *mongoStore) page(ctx context.Context, after *primitive.ObjectID, limit int64) (items []*blogItem, err error) {
+	ctx, done := startOp(ctx, blogCollection, "find_page")
+	defer done(&err)
+	filter := bson.M{}
+	if after != nil {
+		filter["_id"] = bson.M{"$gt": *after}
+	}
+	cursor, err := s.coll.Find(ctx, filter, options.Find().SetSort(bsonx.Doc{{Key: "_id", Value: bsonx.Int32(1)}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		item := &blogItem{}
+		if err := cursor.Decode(item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, cursor.Err()
+}
+
+// each calls fn for every blog in the collection until fn returns an error, fetching batchSize documents
+// per round trip and stopping after limit documents, 0 meaning the driver default and no limit
+func (s *mongoStore) each(ctx context.Context, batchSize int32, limit int64, fn func(*blogItem) error) (err error) {
+	opts := options.Find()
+	if batchSize > 0 {
+		opts.SetBatchSize(batchSize)
+	}
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	// the span and latency cover the query only, fn may block on a slow client for as long as it likes
+	findCtx, done := startOp(ctx, blogCollection, "find")
+	cursor, err := s.coll.Find(findCtx, bsonx.Doc{}, opts)
+	done(&err)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	// fetching the following batches is timed apart from fn and recorded once the cursor is done
+	var fetching time.Duration
+	defer func() { observeStorage(blogCollection, "cursor_next", fetching, err) }()
+	for {
+		start := time.Now()
+		if !cursor.Next(ctx) {
+			fetching += time.Since(start)
+			break
+		}
+		item := &blogItem{}
+		err := cursor.Decode(item)
+		fetching += time.Since(start)
+		if err != nil {
+			return err
+		}
+		if err := fn(item); err == errStopIteration {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
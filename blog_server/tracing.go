@@ -0,0 +1,8 @@
+package main
+
+import (
+	"go.opentelemetry.io/otel"
+)
+
+// tracer starts the storage spans, RPC spans come from the otelgrpc stats handler
+var tracer = otel.Tracer("github.com/k-yomo/blog_with_grpc/blog_server")
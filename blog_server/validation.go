@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleLength   = 200
+	maxContentBytes  = 100 * 1024
+	maxAuthorIDBytes = 64
+)
+
+var (
+	// primitive.ObjectIDFromHex accepts either case
+	objectIDPattern = regexp.MustCompile(`(?i)^[0-9a-f]{24}$`)
+	authorIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+)
+
+// fieldRule is one declarative constraint on a string field of Blog
+type fieldRule struct {
+	field    string
+	get      func(*blogpb.Blog) string
+	required bool
+	// maxRunes and maxBytes of 0 mean no limit
+	maxRunes int
+	maxBytes int
+	pattern  *regexp.Regexp
+	// patternHint tells the client what the pattern accepts
+	patternHint string
+	// singleLine rejects control characters, including line breaks
+	singleLine bool
+}
+
+var (
+	blogIDRule       = fieldRule{field: "id", get: (*blogpb.Blog).GetId, pattern: objectIDPattern, patternHint: "a 24 character hex object ID"}
+	blogAuthorIDRule = fieldRule{field: "author_id", get: (*blogpb.Blog).GetAuthorId, maxBytes: maxAuthorIDBytes, pattern: authorIDPattern, patternHint: "letters, digits and _ . @ -"}
+	blogTitleRule    = fieldRule{field: "title", get: (*blogpb.Blog).GetTitle, required: true, maxRunes: maxTitleLength, singleLine: true}
+	blogContentRule  = fieldRule{field: "content", get: (*blogpb.Blog).GetContent, required: true, maxBytes: maxContentBytes}
+)
+
+// createBlogRules leave author_id optional, it defaults to the caller
+var createBlogRules = []fieldRule{blogAuthorIDRule, blogTitleRule, blogContentRule}
+
+// updateBlogRules leave author_id to UpdateBlog, which checks it only when it changes,
+// so blogs whose author predates blogAuthorIDRule can still be updated
+var updateBlogRules = []fieldRule{withRequired(blogIDRule), blogTitleRule, blogContentRule}
+
+// updateMaskFields are the paths an UpdateBlog mask may name, with the rules of each.
+// id and the output only rendered_html are accepted and ignored, so a PATCH can send back a blog it read.
+var updateMaskFields = map[string][]fieldRule{
+	"author_id":      nil,
+	"title":          {blogTitleRule},
+	"content":        {blogContentRule},
+	"content_format": nil,
+	"id":             nil,
+	"rendered_html":  nil,
+}
+
+// maskedFields returns the paths of mask as a set, nil for an empty mask, which updates every field
+func maskedFields(mask *field_mask.FieldMask) map[string]bool {
+	if len(mask.GetPaths()) == 0 {
+		return nil
+	}
+	fields := make(map[string]bool, len(mask.GetPaths()))
+	for _, path := range mask.GetPaths() {
+		fields[path] = true
+	}
+	return fields
+}
+
+// checkUpdateBlog applies the rules of the fields the request updates, a field left out of the mask may be empty
+func checkUpdateBlog(r *blogpb.UpdateBlogRequest) []*errdetails.BadRequest_FieldViolation {
+	fields := maskedFields(r.GetUpdateMask())
+	if fields == nil {
+		return checkBlog("blog.", r.GetBlog(), updateBlogRules)
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	rules := []fieldRule{withRequired(blogIDRule)}
+	seen := map[string]bool{}
+	for _, path := range r.GetUpdateMask().GetPaths() {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		pathRules, ok := updateMaskFields[path]
+		if !ok {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       "update_mask",
+				Description: fmt.Sprintf("must only name author_id, title, content or content_format, got %q", path),
+			})
+		}
+		rules = append(rules, pathRules...)
+	}
+	return append(violations, checkBlog("blog.", r.GetBlog(), rules)...)
+}
+
+// restoreBlogRules only check the id, backups may hold blogs written before the other rules existed
+var restoreBlogRules = []fieldRule{withRequired(blogIDRule)}
+
+func withRequired(r fieldRule) fieldRule {
+	r.required = true
+	return r
+}
+
+// check returns the violation of the rule by v, or nil
+func (r fieldRule) check(prefix, v string) *errdetails.BadRequest_FieldViolation {
+	violation := func(format string, args ...interface{}) *errdetails.BadRequest_FieldViolation {
+		return &errdetails.BadRequest_FieldViolation{Field: prefix + r.field, Description: fmt.Sprintf(format, args...)}
+	}
+	if v == "" {
+		if r.required {
+			return violation("is required")
+		}
+		return nil
+	}
+	if !utf8.ValidString(v) {
+		return violation("must be valid UTF-8")
+	}
+	if r.maxRunes > 0 && utf8.RuneCountInString(v) > r.maxRunes {
+		return violation("must be at most %d characters", r.maxRunes)
+	}
+	if r.maxBytes > 0 && len(v) > r.maxBytes {
+		return violation("must be at most %d bytes", r.maxBytes)
+	}
+	if r.singleLine && strings.IndexFunc(v, unicode.IsControl) >= 0 {
+		return violation("must not contain control characters or line breaks")
+	}
+	if r.pattern != nil && !r.pattern.MatchString(v) {
+		return violation("must be %s", r.patternHint)
+	}
+	return nil
+}
+
+func checkBlog(prefix string, blog *blogpb.Blog, rules []fieldRule) []*errdetails.BadRequest_FieldViolation {
+	if blog == nil {
+		return []*errdetails.BadRequest_FieldViolation{{Field: strings.TrimSuffix(prefix, "."), Description: "is required"}}
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, r := range rules {
+		if v := r.check(prefix, r.get(blog)); v != nil {
+			violations = append(violations, v)
+		}
+	}
+	// proto3 enums accept any number, only the declared formats can be rendered
+	if _, ok := blogpb.ContentFormat_name[int32(blog.GetContentFormat())]; !ok {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       prefix + "content_format",
+			Description: fmt.Sprintf("must be one of PLAIN, MARKDOWN or HTML, got %d", blog.GetContentFormat()),
+		})
+	}
+	return violations
+}
+
+// validateRequest returns the field violations of a request message, requests without rules have none
+func validateRequest(ctx context.Context, req interface{}) []*errdetails.BadRequest_FieldViolation {
+	switch r := req.(type) {
+	case *blogpb.CreateBlogRequest:
+		violations := checkBlog("blog.", r.GetBlog(), createBlogRules)
+		// the key may also come from metadata, which is held to the same limit
+		if len(idempotencyKey(ctx, r)) > maxIdempotencyKeyLength {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       "idempotency_key",
+				Description: fmt.Sprintf("must be at most %d bytes", maxIdempotencyKeyLength),
+			})
+		}
+		return violations
+	case *blogpb.UpdateBlogRequest:
+		return checkUpdateBlog(r)
+	case *blogpb.RestoreBlogRequest:
+		return checkBlog("blog.", r.GetBlog(), restoreBlogRules)
+	case *blogpb.ListBlogsRequest:
+		if r.GetPageSize() < 0 {
+			return []*errdetails.BadRequest_FieldViolation{{Field: "page_size", Description: "must not be negative"}}
+		}
+	}
+	return nil
+}
+
+func invalidArgument(violations []*errdetails.BadRequest_FieldViolation) error {
+	st := status.New(codes.InvalidArgument, "Invalid request: "+violations[0].GetField()+" "+violations[0].GetDescription())
+	return withDetails(st,
+		&errdetails.BadRequest{FieldViolations: violations},
+		errorInfo(reasonValidationFailed, map[string]string{"field": violations[0].GetField()}),
+	)
+}
+
+func validationUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if violations := validateRequest(ctx, req); len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+	return handler(ctx, req)
+}
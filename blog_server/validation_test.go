@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validID = "5c8a1d5b0190b214360dc031"
+
+// violatedFields returns the fields of violations in order
+func violatedFields(violations []*errdetails.BadRequest_FieldViolation) []string {
+	var fields []string
+	for _, v := range violations {
+		fields = append(fields, v.GetField())
+	}
+	return fields
+}
+
+func TestValidateRequest(t *testing.T) {
+	blog := func(modify func(*blogpb.Blog)) *blogpb.Blog {
+		b := &blogpb.Blog{Id: validID, AuthorId: "alice", Title: "Title", Content: "Content"}
+		if modify != nil {
+			modify(b)
+		}
+		return b
+	}
+	tests := []struct {
+		name string
+		req  interface{}
+		want []string
+	}{
+		{"valid create", &blogpb.CreateBlogRequest{Blog: blog(nil)}, nil},
+		{"create defaults the author", &blogpb.CreateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.AuthorId = "" })}, nil},
+		{"missing blog", &blogpb.CreateBlogRequest{}, []string{"blog"}},
+		{
+			name: "every violation is reported",
+			req: &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{
+				AuthorId:      "alice smith",
+				Title:         "two\nlines",
+				ContentFormat: blogpb.ContentFormat(42),
+			}},
+			want: []string{"blog.author_id", "blog.title", "blog.content", "blog.content_format"},
+		},
+		{"title too long", &blogpb.CreateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.Title = strings.Repeat("é", maxTitleLength+1) })}, []string{"blog.title"}},
+		{"title at the limit", &blogpb.CreateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.Title = strings.Repeat("é", maxTitleLength) })}, nil},
+		{"content too large", &blogpb.CreateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.Content = strings.Repeat("a", maxContentBytes+1) })}, []string{"blog.content"}},
+		{"invalid UTF-8", &blogpb.CreateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.Content = "\xff" })}, []string{"blog.content"}},
+		{"idempotency key too long", &blogpb.CreateBlogRequest{Blog: blog(nil), IdempotencyKey: strings.Repeat("k", maxIdempotencyKeyLength+1)}, []string{"idempotency_key"}},
+
+		{"valid update", &blogpb.UpdateBlogRequest{Blog: blog(nil)}, nil},
+		{"update with an upper case ID", &blogpb.UpdateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.Id = strings.ToUpper(validID) })}, nil},
+		{"update without ID", &blogpb.UpdateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.Id = "" })}, []string{"blog.id"}},
+		{"update with a malformed ID", &blogpb.UpdateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.Id = "not-an-id" })}, []string{"blog.id"}},
+		{"update keeps a legacy author", &blogpb.UpdateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.AuthorId = "Alice Smith" })}, nil},
+		{"full update requires the content", &blogpb.UpdateBlogRequest{Blog: blog(func(b *blogpb.Blog) { b.Content = "" })}, []string{"blog.content"}},
+		{
+			name: "masked update only checks the named fields",
+			req: &blogpb.UpdateBlogRequest{
+				Blog:       &blogpb.Blog{Id: validID, Title: "New title"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"title"}},
+			},
+		},
+		{
+			name: "masked update of an empty field",
+			req: &blogpb.UpdateBlogRequest{
+				Blog:       &blogpb.Blog{Id: validID},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"content", "content"}},
+			},
+			want: []string{"blog.content"},
+		},
+		{
+			name: "masked update of an unknown field",
+			req: &blogpb.UpdateBlogRequest{
+				Blog:       &blogpb.Blog{Id: validID, Title: "x", RenderedHtml: "<p>x</p>"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"title", "rendered_html", "likes"}},
+			},
+			want: []string{"update_mask"},
+		},
+
+		{"restore only needs an ID", &blogpb.RestoreBlogRequest{Blog: &blogpb.Blog{Id: validID, AuthorId: "legacy author"}}, nil},
+		{"restore without ID", &blogpb.RestoreBlogRequest{Blog: &blogpb.Blog{Title: "x"}}, []string{"blog.id"}},
+		{"negative page size", &blogpb.ListBlogsRequest{PageSize: -1}, []string{"page_size"}},
+		{"requests without rules", &blogpb.DeleteBlogRequest{BlogId: "anything"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violatedFields(validateRequest(context.Background(), tt.req))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateRequest() violates %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateIdempotencyKeyHeader(t *testing.T) {
+	req := &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "Title", Content: "Content"}}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotencyKeyHeader, strings.Repeat("k", maxIdempotencyKeyLength+1)))
+	if got := violatedFields(validateRequest(ctx, req)); !reflect.DeepEqual(got, []string{"idempotency_key"}) {
+		t.Errorf("validateRequest() violates %v, want the idempotency key", got)
+	}
+}
+
+func TestInvalidArgument(t *testing.T) {
+	violations := []*errdetails.BadRequest_FieldViolation{
+		{Field: "blog.title", Description: "is required"},
+		{Field: "blog.content", Description: "is required"},
+	}
+	st := status.Convert(invalidArgument(violations))
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %v", st.Code())
+	}
+	if want := "Invalid request: blog.title is required"; st.Message() != want {
+		t.Errorf("message = %q, want %q", st.Message(), want)
+	}
+	var badRequest *errdetails.BadRequest
+	var info *errdetails.ErrorInfo
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.BadRequest:
+			badRequest = d
+		case *errdetails.ErrorInfo:
+			info = d
+		}
+	}
+	if got := violatedFields(badRequest.GetFieldViolations()); !reflect.DeepEqual(got, []string{"blog.title", "blog.content"}) {
+		t.Errorf("BadRequest violates %v", got)
+	}
+	if info.GetReason() != reasonValidationFailed || info.GetMetadata()["field"] != "blog.title" {
+		t.Errorf("ErrorInfo = %v", info)
+	}
+}
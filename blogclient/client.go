@@ -0,0 +1,125 @@
+// Package blogclient is a Go client for blog_server.
+// It wraps the generated blogpb.BlogServiceClient with authentication, a service config with
+// per method timeouts, retries and hedging, a ListBlog iterator and typed NOT_FOUND errors.
+package blogclient
+
+import (
+	"context"
+	"fmt"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"google.golang.org/grpc"
+	grpccredentials "google.golang.org/grpc/credentials"
+)
+
+// Client calls BlogService over one connection, it is safe for concurrent use
+type Client struct {
+	cc      *grpc.ClientConn
+	service blogpb.BlogServiceClient
+	opts    *options
+}
+
+// Dial connects to blog_server at target, e.g. "localhost:50051"
+func Dial(target string, opts ...Option) (*Client, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	config := o.serviceConfig
+	if config == "" {
+		config = defaultServiceConfig(o)
+	}
+	hedges, err := parseHedges(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(config),
+		grpc.WithChainUnaryInterceptor(hedgingUnaryInterceptor(hedges), o.retryUnaryInterceptor, o.rateLimitUnaryInterceptor),
+	}
+	if o.tls != nil {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(grpccredentials.NewTLS(o.tls)))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	}
+	if o.credentials != nil {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(o.credentials))
+	}
+	dialOpts = append(dialOpts, o.dialOptions...)
+
+	cc, err := grpc.Dial(target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s: %v", target, err)
+	}
+	return &Client{cc: cc, service: blogpb.NewBlogServiceClient(cc), opts: o}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.cc.Close()
+}
+
+// Target is the address the client was dialed with
+func (c *Client) Target() string {
+	return c.cc.Target()
+}
+
+// Service returns the generated client, its calls get the same timeouts, retries and hedging
+func (c *Client) Service() blogpb.BlogServiceClient {
+	return c.service
+}
+
+// CreateBlog creates blog. With an idempotency key, retries of the call return the blog created first.
+func (c *Client) CreateBlog(ctx context.Context, blog *blogpb.Blog, idempotencyKey string) (*blogpb.Blog, error) {
+	res, err := c.service.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: blog, IdempotencyKey: idempotencyKey})
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return res.GetBlog(), nil
+}
+
+func (c *Client) ReadBlog(ctx context.Context, blogID string) (*blogpb.Blog, error) {
+	res, err := c.service.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: blogID})
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return res.GetBlog(), nil
+}
+
+// UpdateBlog replaces the blog with the ID of blog
+func (c *Client) UpdateBlog(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	res, err := c.service.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: blog})
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return res.GetBlog(), nil
+}
+
+func (c *Client) DeleteBlog(ctx context.Context, blogID string) error {
+	_, err := c.service.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: blogID})
+	return convertError(err)
+}
+
+// ListBlogs returns one page of blogs and the token of the next page, empty on the last page
+func (c *Client) ListBlogs(ctx context.Context, pageSize int32, pageToken string) ([]*blogpb.Blog, string, error) {
+	res, err := c.service.ListBlogs(ctx, &blogpb.ListBlogsRequest{PageSize: pageSize, PageToken: pageToken})
+	if err != nil {
+		return nil, "", convertError(err)
+	}
+	return res.GetBlogs(), res.GetNextPageToken(), nil
+}
+
+// RestoreBlog writes blog under its ID, created is false when it replaced a blog. It requires an admin.
+func (c *Client) RestoreBlog(ctx context.Context, blog *blogpb.Blog) (created bool, err error) {
+	res, err := c.service.RestoreBlog(ctx, &blogpb.RestoreBlogRequest{Blog: blog})
+	if err != nil {
+		return false, convertError(err)
+	}
+	return res.GetCreated(), nil
+}
+
+// ListBlog streams all blogs, the stream is not bounded by the timeout of unary calls
+func (c *Client) ListBlog(ctx context.Context) *BlogIterator {
+	return newBlogIterator(ctx, c.service)
+}
@@ -0,0 +1,51 @@
+package blogclient
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NotFoundError is returned when the requested resource does not exist
+type NotFoundError struct {
+	// ResourceType and ResourceName come from the ResourceInfo detail, e.g. "blog" and the blog ID
+	ResourceType string
+	ResourceName string
+	status       *status.Status
+}
+
+func (e *NotFoundError) Error() string {
+	if e.ResourceName == "" {
+		return e.status.Message()
+	}
+	return fmt.Sprintf("%s %s not found", e.ResourceType, e.ResourceName)
+}
+
+// GRPCStatus keeps status.FromError and status.Code working on the typed error
+func (e *NotFoundError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// IsNotFound reports whether err is or wraps a NotFoundError
+func IsNotFound(err error) bool {
+	var nf *NotFoundError
+	return errors.As(err, &nf)
+}
+
+// convertError turns NOT_FOUND statuses into a NotFoundError, other errors are returned as is
+func convertError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.NotFound {
+		return err
+	}
+	nf := &NotFoundError{status: st}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ResourceInfo); ok {
+			nf.ResourceType = info.GetResourceType()
+			nf.ResourceName = info.GetResourceName()
+		}
+	}
+	return nf
+}
@@ -0,0 +1,88 @@
+package blogclient
+
+import (
+	"context"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"io"
+)
+
+// truncatedTrailer is set by the server when it stopped the stream at its message cap
+const truncatedTrailer = "x-list-truncated"
+
+// BlogIterator walks the ListBlog stream:
+//
+//	it := client.ListBlog(ctx)
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Println(it.Blog().GetTitle())
+//	}
+//	if err := it.Err(); err != nil { ... }
+type BlogIterator struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	service blogpb.BlogServiceClient
+	stream  blogpb.BlogService_ListBlogClient
+	blog    *blogpb.Blog
+	err     error
+	done    bool
+}
+
+func newBlogIterator(ctx context.Context, service blogpb.BlogServiceClient) *BlogIterator {
+	ctx, cancel := context.WithCancel(ctx)
+	return &BlogIterator{ctx: ctx, cancel: cancel, service: service}
+}
+
+// Next advances to the next blog, it returns false at the end of the stream or on an error.
+// A stream failing before its first blog is retried by the service config.
+func (it *BlogIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if it.stream == nil {
+		if it.stream, it.err = it.service.ListBlog(it.ctx, &blogpb.ListBlogRequest{}); it.err != nil {
+			it.err = convertError(it.err)
+			return it.finish()
+		}
+	}
+	res, err := it.stream.Recv()
+	if err == io.EOF {
+		return it.finish()
+	}
+	if err != nil {
+		it.err = convertError(err)
+		return it.finish()
+	}
+	it.blog = res.GetBlog()
+	return true
+}
+
+func (it *BlogIterator) finish() bool {
+	it.done = true
+	it.blog = nil
+	return false
+}
+
+// Blog is the blog Next advanced to
+func (it *BlogIterator) Blog() *blogpb.Blog {
+	return it.blog
+}
+
+// Err is the error that ended the iteration, nil at the end of the stream
+func (it *BlogIterator) Err() error {
+	return it.err
+}
+
+// Truncated reports whether the server stopped the stream at its message cap, valid once Next returned false
+func (it *BlogIterator) Truncated() bool {
+	if !it.done || it.err != nil || it.stream == nil {
+		return false
+	}
+	v := it.stream.Trailer().Get(truncatedTrailer)
+	return len(v) > 0 && v[0] == "true"
+}
+
+// Close stops the stream, it can be called before the end is reached
+func (it *BlogIterator) Close() {
+	it.cancel()
+	it.done = true
+}
@@ -0,0 +1,123 @@
+package blogclient
+
+import (
+	"context"
+	"crypto/tls"
+	"google.golang.org/grpc"
+	"time"
+)
+
+type options struct {
+	tls         *tls.Config
+	credentials credentials
+	timeout     time.Duration
+	maxRetries  int
+	backoff     backoff
+	// maxRateLimitWaits is how often a call rejected with RESOURCE_EXHAUSTED waits for its RetryInfo delay
+	maxRateLimitWaits int
+	// serviceConfig replaces the config built from timeout, maxRetries and backoff
+	serviceConfig string
+	dialOptions   []grpc.DialOption
+}
+
+func defaultOptions() *options {
+	return &options{
+		timeout:           10 * time.Second,
+		maxRetries:        3,
+		backoff:           backoff{base: 100 * time.Millisecond, max: 2 * time.Second},
+		maxRateLimitWaits: 10,
+	}
+}
+
+// Option configures a Client
+type Option func(*options)
+
+// WithTLS connects over TLS, the connection is plaintext by default
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tls = config
+	}
+}
+
+// WithToken authenticates every call with a JWT
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.credentials = tokenCredentials(token)
+	}
+}
+
+// WithAPIKey authenticates every call with an API key, it replaces WithToken
+func WithAPIKey(key string) Option {
+	return func(o *options) {
+		o.credentials = apiKeyCredentials(key)
+	}
+}
+
+// WithTimeout bounds every unary call, 0 disables it. Defaults to 10s.
+// A shorter deadline on the context of a call still applies.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRetries sets how often a read failing with UNAVAILABLE is retried and the backoff between attempts,
+// 0 retries disables them. Defaults to 3 retries starting at 100ms, doubling up to 2s.
+// gRPC caps the attempts of a call at 5. Writes are only retried when they carry an idempotency key.
+func WithRetries(max int, base, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.maxRetries = max
+		o.backoff = backoff{base: base, max: maxBackoff}
+	}
+}
+
+// WithRateLimitWaits sets how often a call rejected by the server's rate limit waits for the delay the server
+// asks for and is sent again, 0 returns RESOURCE_EXHAUSTED right away. Defaults to 10.
+// Any call can be sent again, the server rejects it before doing anything.
+func WithRateLimitWaits(max int) Option {
+	return func(o *options) {
+		o.maxRateLimitWaits = max
+	}
+}
+
+// WithServiceConfig replaces the default service config, see
+// https://github.com/grpc/grpc/blob/master/doc/service_config.md.
+// Timeouts and retry policies are applied by gRPC, hedging policies by this package.
+func WithServiceConfig(json string) Option {
+	return func(o *options) {
+		o.serviceConfig = json
+	}
+}
+
+// WithDialOptions passes extra options to grpc.Dial, e.g. a stats handler
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// credentials are the per RPC credentials understood by blog_server
+type credentials interface {
+	GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error)
+	RequireTransportSecurity() bool
+}
+
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+type apiKeyCredentials string
+
+func (k apiKeyCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"x-api-key": string(k)}, nil
+}
+
+func (apiKeyCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,94 @@
+package blogclient
+
+import (
+	"context"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"time"
+)
+
+// backoff doubles from base up to max, with full jitter
+type backoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+func (b backoff) delay(attempt int) time.Duration {
+	d := b.max
+	if attempt < 32 && b.base<<uint(attempt) < b.max {
+		d = b.base << uint(attempt)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// wait sleeps for the backoff of attempt, returning early when ctx is done
+func (b backoff) wait(ctx context.Context, attempt int) error {
+	t := time.NewTimer(b.delay(attempt))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryUnaryInterceptor retries creates with an idempotency key failing with UNAVAILABLE.
+// Other calls are retried by the retry policies of the service config, which cannot tell creates with a key from ones without.
+func (o *options) retryUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	create, ok := req.(*blogpb.CreateBlogRequest)
+	if !ok || create.GetIdempotencyKey() == "" {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	for attempt := 0; ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if status.Code(err) != codes.Unavailable || attempt >= o.maxRetries {
+			return err
+		}
+		if o.backoff.wait(ctx, attempt) != nil {
+			return err
+		}
+	}
+}
+
+// retryDelay returns the delay of the RetryInfo detail of err, false when it has none
+func retryDelay(err error) (time.Duration, bool) {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// rateLimitUnaryInterceptor sends a call rejected with RESOURCE_EXHAUSTED again after the delay in its RetryInfo.
+// It gives up when the delay would pass the deadline of ctx.
+func (o *options) rateLimitUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	for attempt := 0; ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if status.Code(err) != codes.ResourceExhausted || attempt >= o.maxRateLimitWaits {
+			return err
+		}
+		delay, ok := retryDelay(err)
+		if !ok {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package blogclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"path"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const serviceName = "blog.BlogService"
+
+// serviceConfig is the subset of the gRPC service config used by this package, see
+// https://github.com/grpc/grpc/blob/master/doc/service_config.md
+type serviceConfig struct {
+	MethodConfig []methodConfig `json:"methodConfig"`
+}
+
+type methodConfig struct {
+	Name          []methodName   `json:"name"`
+	Timeout       string         `json:"timeout,omitempty"`
+	RetryPolicy   *retryPolicy   `json:"retryPolicy,omitempty"`
+	HedgingPolicy *hedgingPolicy `json:"hedgingPolicy,omitempty"`
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// hedgingPolicy is part of the service config spec but not implemented by grpc-go,
+// this package implements it with hedgingUnaryInterceptor
+type hedgingPolicy struct {
+	MaxAttempts         int      `json:"maxAttempts"`
+	HedgingDelay        string   `json:"hedgingDelay"`
+	NonFatalStatusCodes []string `json:"nonFatalStatusCodes"`
+}
+
+// defaultServiceConfig builds the service config from the options:
+// reads and restores are retried, ReadBlog is hedged and other writes are never retried by gRPC.
+// CreateBlog with an idempotency key is retried by retryUnaryInterceptor instead, a service config cannot look at the request.
+func defaultServiceConfig(o *options) string {
+	var retry *retryPolicy
+	if o.maxRetries > 0 && o.backoff.base > 0 {
+		retry = &retryPolicy{
+			MaxAttempts:          o.maxRetries + 1,
+			InitialBackoff:       formatDuration(o.backoff.base),
+			MaxBackoff:           formatDuration(o.backoff.max),
+			BackoffMultiplier:    2,
+			RetryableStatusCodes: []string{"UNAVAILABLE"},
+		}
+	}
+	var timeout string
+	if o.timeout > 0 {
+		timeout = formatDuration(o.timeout)
+	}
+	methods := func(names ...string) []methodName {
+		var m []methodName
+		for _, name := range names {
+			m = append(m, methodName{Service: serviceName, Method: name})
+		}
+		return m
+	}
+
+	config := serviceConfig{MethodConfig: []methodConfig{
+		{
+			Name:    methods("ReadBlog"),
+			Timeout: timeout,
+			HedgingPolicy: &hedgingPolicy{
+				MaxAttempts:         3,
+				HedgingDelay:        "0.2s",
+				NonFatalStatusCodes: []string{"UNAVAILABLE"},
+			},
+		},
+		// a restore writes the same blog under the same ID, repeating it is harmless
+		{Name: methods("ListBlogs", "RestoreBlog"), Timeout: timeout, RetryPolicy: retry},
+		// a stream is only retried until the first blog arrives, and may run longer than a unary call
+		{Name: methods("ListBlog"), RetryPolicy: retry},
+		{Name: methods("CreateBlog", "UpdateBlog", "DeleteBlog"), Timeout: timeout},
+	}}
+	b, _ := json.Marshal(config)
+	return string(b)
+}
+
+func formatDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// hedge is a parsed hedgingPolicy
+type hedge struct {
+	maxAttempts int
+	delay       time.Duration
+	nonFatal    map[codes.Code]bool
+}
+
+// parseHedges returns the hedging policies of config by full method name,
+// a policy for a whole service is stored under "/<service>/"
+func parseHedges(config string) (map[string]*hedge, error) {
+	var sc serviceConfig
+	if err := json.Unmarshal([]byte(config), &sc); err != nil {
+		return nil, fmt.Errorf("invalid service config: %v", err)
+	}
+	hedges := map[string]*hedge{}
+	for _, mc := range sc.MethodConfig {
+		p := mc.HedgingPolicy
+		if p == nil {
+			continue
+		}
+		h := &hedge{maxAttempts: p.MaxAttempts, nonFatal: map[codes.Code]bool{}}
+		if h.maxAttempts < 1 {
+			return nil, fmt.Errorf("invalid service config: hedgingPolicy.maxAttempts must be positive")
+		}
+		if p.HedgingDelay != "" {
+			d, err := time.ParseDuration(p.HedgingDelay)
+			if err != nil {
+				return nil, fmt.Errorf("invalid service config: hedgingDelay: %v", err)
+			}
+			h.delay = d
+		}
+		for _, name := range p.NonFatalStatusCodes {
+			var c codes.Code
+			if err := c.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+				return nil, fmt.Errorf("invalid service config: %v", err)
+			}
+			h.nonFatal[c] = true
+		}
+		for _, n := range mc.Name {
+			hedges["/"+n.Service+"/"+n.Method] = h
+		}
+	}
+	return hedges, nil
+}
+
+// hedgingUnaryInterceptor sends up to maxAttempts copies of a call, each hedgingDelay after the previous one
+// or right after a non fatal failure, and returns the first success
+func hedgingUnaryInterceptor(hedges map[string]*hedge) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		h, ok := hedges[method]
+		if !ok {
+			h, ok = hedges[method[:len(method)-len(path.Base(method))]]
+		}
+		if !ok || h.maxAttempts == 1 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		// the losing attempts are cancelled once a result is taken
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		type result struct {
+			reply proto.Message
+			err   error
+		}
+		results := make(chan result, h.maxAttempts)
+		sent, pending := 0, 0
+		send := func() {
+			sent++
+			pending++
+			r := reflect.New(reflect.TypeOf(reply).Elem()).Interface().(proto.Message)
+			go func() {
+				results <- result{reply: r, err: invoker(ctx, method, req, r, cc, opts...)}
+			}()
+		}
+
+		send()
+		timer := time.NewTimer(h.delay)
+		defer timer.Stop()
+		var err error
+		for pending > 0 {
+			select {
+			case <-timer.C:
+				if sent < h.maxAttempts {
+					send()
+					timer.Reset(h.delay)
+				}
+			case r := <-results:
+				pending--
+				if r.err == nil {
+					proto.Merge(reply.(proto.Message), r.reply)
+					return nil
+				}
+				err = r.err
+				if !h.nonFatal[status.Code(r.err)] {
+					return err
+				}
+				if sent < h.maxAttempts {
+					send()
+					if !timer.Stop() {
+						select {
+						case <-timer.C:
+						default:
+						}
+					}
+					timer.Reset(h.delay)
+				}
+			}
+		}
+		return err
+	}
+}
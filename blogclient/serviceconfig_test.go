@@ -0,0 +1,156 @@
+package blogclient
+
+import (
+	"context"
+	"fmt"
+	"github.com/k-yomo/blog_with_grpc/blogpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hedgedServer answers ReadBlog with readBlog, attempts are numbered from 1 in the order they arrive
+type hedgedServer struct {
+	blogpb.BlogServiceServer
+	readBlog func(ctx context.Context, attempt int) (*blogpb.ReadBlogResponse, error)
+
+	mu        sync.Mutex
+	attempts  int
+	cancelled int
+}
+
+func (s *hedgedServer) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (*blogpb.ReadBlogResponse, error) {
+	s.mu.Lock()
+	s.attempts++
+	attempt := s.attempts
+	s.mu.Unlock()
+	return s.readBlog(ctx, attempt)
+}
+
+// block waits for the client to cancel the attempt and counts it
+func (s *hedgedServer) block(ctx context.Context) (*blogpb.ReadBlogResponse, error) {
+	<-ctx.Done()
+	s.mu.Lock()
+	s.cancelled++
+	s.mu.Unlock()
+	return nil, ctx.Err()
+}
+
+func (s *hedgedServer) counts() (attempts, cancelled int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts, s.cancelled
+}
+
+// dialHedged serves s over an in-memory listener and dials it with ReadBlog hedged maxAttempts times, delay apart
+func dialHedged(t *testing.T, s *hedgedServer, maxAttempts int, delay time.Duration) *Client {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	blogpb.RegisterBlogServiceServer(srv, s)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	config := fmt.Sprintf(`{"methodConfig": [{
+		"name": [{"service": %q, "method": "ReadBlog"}],
+		"hedgingPolicy": {"maxAttempts": %d, "hedgingDelay": %q, "nonFatalStatusCodes": ["UNAVAILABLE"]}
+	}]}`, serviceName, maxAttempts, formatDuration(delay))
+	c, err := Dial("bufnet",
+		WithServiceConfig(config),
+		WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// readHedged reads a blog with a context that lives until the test ends,
+// so only the interceptor can cancel the losing attempts
+func readHedged(t *testing.T, c *Client) (*blogpb.ReadBlogResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return c.Service().ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: "1"})
+}
+
+func TestHedgingCancelsLosingAttempts(t *testing.T) {
+	s := &hedgedServer{}
+	s.readBlog = func(ctx context.Context, attempt int) (*blogpb.ReadBlogResponse, error) {
+		if attempt == 2 {
+			return &blogpb.ReadBlogResponse{Blog: &blogpb.Blog{Id: "1", Title: "second"}}, nil
+		}
+		return s.block(ctx)
+	}
+	c := dialHedged(t, s, 3, 20*time.Millisecond)
+
+	res, err := readHedged(t, c)
+	if err != nil {
+		t.Fatalf("ReadBlog() error = %v", err)
+	}
+	if got := res.GetBlog().GetTitle(); got != "second" {
+		t.Errorf("ReadBlog() returned the blog of attempt %q, want the second", got)
+	}
+
+	// every attempt but the winner ends on the server once the result is taken
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		attempts, cancelled := s.counts()
+		if attempts >= 2 && cancelled == attempts-1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d attempts were cancelled, want all but the winner", cancelled, attempts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHedgingStatusCodes(t *testing.T) {
+	t.Run("a non fatal failure sends the next attempt at once", func(t *testing.T) {
+		s := &hedgedServer{readBlog: func(ctx context.Context, attempt int) (*blogpb.ReadBlogResponse, error) {
+			if attempt == 1 {
+				return nil, status.Error(codes.Unavailable, "down")
+			}
+			return &blogpb.ReadBlogResponse{Blog: &blogpb.Blog{Id: "1"}}, nil
+		}}
+		// the delay outlasts the test, only the failure can trigger the second attempt
+		c := dialHedged(t, s, 2, time.Hour)
+		if _, err := readHedged(t, c); err != nil {
+			t.Fatalf("ReadBlog() error = %v", err)
+		}
+		if attempts, _ := s.counts(); attempts != 2 {
+			t.Errorf("%d attempts, want 2", attempts)
+		}
+	})
+
+	t.Run("a fatal failure is returned without hedging", func(t *testing.T) {
+		s := &hedgedServer{readBlog: func(ctx context.Context, attempt int) (*blogpb.ReadBlogResponse, error) {
+			return nil, status.Error(codes.NotFound, "no blog")
+		}}
+		c := dialHedged(t, s, 3, time.Hour)
+		if _, err := readHedged(t, c); status.Code(err) != codes.NotFound {
+			t.Fatalf("ReadBlog() error = %v, want NOT_FOUND", err)
+		}
+		if attempts, _ := s.counts(); attempts != 1 {
+			t.Errorf("%d attempts, want 1", attempts)
+		}
+	})
+
+	t.Run("the last error is returned when every attempt fails", func(t *testing.T) {
+		s := &hedgedServer{readBlog: func(ctx context.Context, attempt int) (*blogpb.ReadBlogResponse, error) {
+			return nil, status.Errorf(codes.Unavailable, "attempt %d", attempt)
+		}}
+		c := dialHedged(t, s, 3, time.Hour)
+		_, err := readHedged(t, c)
+		if status.Code(err) != codes.Unavailable || status.Convert(err).Message() != "attempt 3" {
+			t.Fatalf("ReadBlog() error = %v, want UNAVAILABLE from attempt 3", err)
+		}
+	})
+}
@@ -7,6 +7,8 @@ import (
 	context "context"
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	field_mask "google.golang.org/genproto/protobuf/field_mask"
 	grpc "google.golang.org/grpc"
 	math "math"
 )
@@ -22,11 +24,48 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// ContentFormat tells the server how to render the content of a blog
+type ContentFormat int32
+
+const (
+	// treated as PLAIN on create, keeps the stored format on update
+	ContentFormat_CONTENT_FORMAT_UNSPECIFIED ContentFormat = 0
+	ContentFormat_PLAIN                      ContentFormat = 1
+	// CommonMark with GitHub extensions, fenced code blocks are syntax highlighted
+	ContentFormat_MARKDOWN ContentFormat = 2
+	ContentFormat_HTML     ContentFormat = 3
+)
+
+var ContentFormat_name = map[int32]string{
+	0: "CONTENT_FORMAT_UNSPECIFIED",
+	1: "PLAIN",
+	2: "MARKDOWN",
+	3: "HTML",
+}
+
+var ContentFormat_value = map[string]int32{
+	"CONTENT_FORMAT_UNSPECIFIED": 0,
+	"PLAIN":                      1,
+	"MARKDOWN":                   2,
+	"HTML":                       3,
+}
+
+func (x ContentFormat) String() string {
+	return proto.EnumName(ContentFormat_name, int32(x))
+}
+
+func (ContentFormat) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_1cd072c3eda6f7ba, []int{0}
+}
+
 type Blog struct {
-	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	AuthorId             string   `protobuf:"bytes,2,opt,name=author_id,json=authorId,proto3" json:"author_id,omitempty"`
-	Title                string   `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
-	Content              string   `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	Id            string        `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	AuthorId      string        `protobuf:"bytes,2,opt,name=author_id,json=authorId,proto3" json:"author_id,omitempty"`
+	Title         string        `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Content       string        `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	ContentFormat ContentFormat `protobuf:"varint,5,opt,name=content_format,json=contentFormat,proto3,enum=blog.ContentFormat" json:"content_format,omitempty"`
+	// output only, the content rendered to sanitized HTML, ignored in requests
+	RenderedHtml         string   `protobuf:"bytes,6,opt,name=rendered_html,json=renderedHtml,proto3" json:"rendered_html,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -85,8 +124,25 @@ func (m *Blog) GetContent() string {
 	return ""
 }
 
+func (m *Blog) GetContentFormat() ContentFormat {
+	if m != nil {
+		return m.ContentFormat
+	}
+	return ContentFormat_CONTENT_FORMAT_UNSPECIFIED
+}
+
+func (m *Blog) GetRenderedHtml() string {
+	if m != nil {
+		return m.RenderedHtml
+	}
+	return ""
+}
+
 type CreateBlogRequest struct {
-	Blog                 *Blog    `protobuf:"bytes,1,opt,name=blog,proto3" json:"blog,omitempty"`
+	Blog *Blog `protobuf:"bytes,1,opt,name=blog,proto3" json:"blog,omitempty"`
+	// retries with the same key and blog return the original response instead of creating a duplicate,
+	// can also be sent in the idempotency-key metadata
+	IdempotencyKey       string   `protobuf:"bytes,2,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -124,6 +180,13 @@ func (m *CreateBlogRequest) GetBlog() *Blog {
 	return nil
 }
 
+func (m *CreateBlogRequest) GetIdempotencyKey() string {
+	if m != nil {
+		return m.IdempotencyKey
+	}
+	return ""
+}
+
 type CreateBlogResponse struct {
 	Blog                 *Blog    `protobuf:"bytes,1,opt,name=blog,proto3" json:"blog,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -242,10 +305,13 @@ func (m *ReadBlogResponse) GetBlog() *Blog {
 }
 
 type UpdateBlogRequest struct {
-	Blog                 *Blog    `protobuf:"bytes,1,opt,name=blog,proto3" json:"blog,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Blog *Blog `protobuf:"bytes,1,opt,name=blog,proto3" json:"blog,omitempty"`
+	// fields of blog to update: author_id, title, content and content_format, id is ignored.
+	// Empty replaces all of them. PATCH fills it from the fields present in the request body.
+	UpdateMask           *field_mask.FieldMask `protobuf:"bytes,2,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
 }
 
 func (m *UpdateBlogRequest) Reset()         { *m = UpdateBlogRequest{} }
@@ -280,6 +346,13 @@ func (m *UpdateBlogRequest) GetBlog() *Blog {
 	return nil
 }
 
+func (m *UpdateBlogRequest) GetUpdateMask() *field_mask.FieldMask {
+	if m != nil {
+		return m.UpdateMask
+	}
+	return nil
+}
+
 type UpdateBlogResponse struct {
 	Blog                 *Blog    `protobuf:"bytes,1,opt,name=blog,proto3" json:"blog,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -467,7 +540,193 @@ func (m *ListBlogResponse) GetBlog() *Blog {
 	return nil
 }
 
+type ListBlogsRequest struct {
+	// defaults to 20, at most 100
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page, empty for the first page
+	PageToken            string   `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListBlogsRequest) Reset()         { *m = ListBlogsRequest{} }
+func (m *ListBlogsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListBlogsRequest) ProtoMessage()    {}
+func (*ListBlogsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1cd072c3eda6f7ba, []int{11}
+}
+
+func (m *ListBlogsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListBlogsRequest.Unmarshal(m, b)
+}
+func (m *ListBlogsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListBlogsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListBlogsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListBlogsRequest.Merge(m, src)
+}
+func (m *ListBlogsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListBlogsRequest.Size(m)
+}
+func (m *ListBlogsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListBlogsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListBlogsRequest proto.InternalMessageInfo
+
+func (m *ListBlogsRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListBlogsRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+type ListBlogsResponse struct {
+	Blogs []*Blog `protobuf:"bytes,1,rep,name=blogs,proto3" json:"blogs,omitempty"`
+	// empty on the last page
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListBlogsResponse) Reset()         { *m = ListBlogsResponse{} }
+func (m *ListBlogsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListBlogsResponse) ProtoMessage()    {}
+func (*ListBlogsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1cd072c3eda6f7ba, []int{12}
+}
+
+func (m *ListBlogsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListBlogsResponse.Unmarshal(m, b)
+}
+func (m *ListBlogsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListBlogsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListBlogsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListBlogsResponse.Merge(m, src)
+}
+func (m *ListBlogsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListBlogsResponse.Size(m)
+}
+func (m *ListBlogsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListBlogsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListBlogsResponse proto.InternalMessageInfo
+
+func (m *ListBlogsResponse) GetBlogs() []*Blog {
+	if m != nil {
+		return m.Blogs
+	}
+	return nil
+}
+
+func (m *ListBlogsResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+type RestoreBlogRequest struct {
+	// must have the id it had when it was backed up
+	Blog                 *Blog    `protobuf:"bytes,1,opt,name=blog,proto3" json:"blog,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RestoreBlogRequest) Reset()         { *m = RestoreBlogRequest{} }
+func (m *RestoreBlogRequest) String() string { return proto.CompactTextString(m) }
+func (*RestoreBlogRequest) ProtoMessage()    {}
+func (*RestoreBlogRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1cd072c3eda6f7ba, []int{13}
+}
+
+func (m *RestoreBlogRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RestoreBlogRequest.Unmarshal(m, b)
+}
+func (m *RestoreBlogRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RestoreBlogRequest.Marshal(b, m, deterministic)
+}
+func (m *RestoreBlogRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RestoreBlogRequest.Merge(m, src)
+}
+func (m *RestoreBlogRequest) XXX_Size() int {
+	return xxx_messageInfo_RestoreBlogRequest.Size(m)
+}
+func (m *RestoreBlogRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RestoreBlogRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RestoreBlogRequest proto.InternalMessageInfo
+
+func (m *RestoreBlogRequest) GetBlog() *Blog {
+	if m != nil {
+		return m.Blog
+	}
+	return nil
+}
+
+type RestoreBlogResponse struct {
+	Blog *Blog `protobuf:"bytes,1,opt,name=blog,proto3" json:"blog,omitempty"`
+	// false when a blog with the id was replaced
+	Created              bool     `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RestoreBlogResponse) Reset()         { *m = RestoreBlogResponse{} }
+func (m *RestoreBlogResponse) String() string { return proto.CompactTextString(m) }
+func (*RestoreBlogResponse) ProtoMessage()    {}
+func (*RestoreBlogResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1cd072c3eda6f7ba, []int{14}
+}
+
+func (m *RestoreBlogResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RestoreBlogResponse.Unmarshal(m, b)
+}
+func (m *RestoreBlogResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RestoreBlogResponse.Marshal(b, m, deterministic)
+}
+func (m *RestoreBlogResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RestoreBlogResponse.Merge(m, src)
+}
+func (m *RestoreBlogResponse) XXX_Size() int {
+	return xxx_messageInfo_RestoreBlogResponse.Size(m)
+}
+func (m *RestoreBlogResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RestoreBlogResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RestoreBlogResponse proto.InternalMessageInfo
+
+func (m *RestoreBlogResponse) GetBlog() *Blog {
+	if m != nil {
+		return m.Blog
+	}
+	return nil
+}
+
+func (m *RestoreBlogResponse) GetCreated() bool {
+	if m != nil {
+		return m.Created
+	}
+	return false
+}
+
 func init() {
+	proto.RegisterEnum("blog.ContentFormat", ContentFormat_name, ContentFormat_value)
 	proto.RegisterType((*Blog)(nil), "blog.Blog")
 	proto.RegisterType((*CreateBlogRequest)(nil), "blog.CreateBlogRequest")
 	proto.RegisterType((*CreateBlogResponse)(nil), "blog.CreateBlogResponse")
@@ -479,34 +738,65 @@ func init() {
 	proto.RegisterType((*DeleteBlogResponse)(nil), "blog.DeleteBlogResponse")
 	proto.RegisterType((*ListBlogRequest)(nil), "blog.ListBlogRequest")
 	proto.RegisterType((*ListBlogResponse)(nil), "blog.ListBlogResponse")
+	proto.RegisterType((*ListBlogsRequest)(nil), "blog.ListBlogsRequest")
+	proto.RegisterType((*ListBlogsResponse)(nil), "blog.ListBlogsResponse")
+	proto.RegisterType((*RestoreBlogRequest)(nil), "blog.RestoreBlogRequest")
+	proto.RegisterType((*RestoreBlogResponse)(nil), "blog.RestoreBlogResponse")
 }
 
 func init() { proto.RegisterFile("blogpb/blog.proto", fileDescriptor_1cd072c3eda6f7ba) }
 
 var fileDescriptor_1cd072c3eda6f7ba = []byte{
-	// 349 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x53, 0xc1, 0x4e, 0xc2, 0x40,
-	0x14, 0x0c, 0x15, 0xa1, 0x0c, 0x89, 0xd8, 0x8d, 0x4a, 0x83, 0x89, 0x31, 0x9c, 0x8c, 0x51, 0x34,
-	0xe0, 0xc5, 0x78, 0x20, 0x41, 0x2f, 0x24, 0x9e, 0x30, 0x5e, 0xbc, 0x10, 0x60, 0x5f, 0xb0, 0x49,
-	0xc3, 0xd6, 0x76, 0xf1, 0x1b, 0xfd, 0x2c, 0xb3, 0xbb, 0xac, 0xad, 0x2d, 0xc4, 0x7a, 0x6a, 0xdf,
-	0xdb, 0x79, 0x6f, 0x66, 0x67, 0xb2, 0xf0, 0xe6, 0xa1, 0x58, 0x46, 0xf3, 0x1b, 0xf5, 0xe9, 0x45,
-	0xb1, 0x90, 0x82, 0x55, 0xd5, 0x7f, 0x77, 0x81, 0xea, 0x28, 0x14, 0x4b, 0x76, 0x00, 0x27, 0xe0,
-	0x7e, 0xe5, 0xbc, 0x72, 0xd1, 0x98, 0x38, 0x01, 0x67, 0xa7, 0x68, 0xcc, 0xd6, 0xf2, 0x5d, 0xc4,
-	0xd3, 0x80, 0xfb, 0x8e, 0x6e, 0xbb, 0xa6, 0x31, 0xe6, 0xec, 0x08, 0xfb, 0x32, 0x90, 0x21, 0xf9,
-	0x7b, 0xfa, 0xc0, 0x14, 0xcc, 0x47, 0x7d, 0x21, 0x56, 0x92, 0x56, 0xd2, 0xaf, 0xea, 0xbe, 0x2d,
-	0xbb, 0x03, 0x78, 0x8f, 0x31, 0xcd, 0x24, 0x29, 0xaa, 0x09, 0x7d, 0xac, 0x29, 0x91, 0xec, 0x0c,
-	0x5a, 0x81, 0xe6, 0x6c, 0xf6, 0xd1, 0xd3, 0xd2, 0x34, 0xc0, 0x28, 0xbb, 0x03, 0xcb, 0x0e, 0x25,
-	0x91, 0x58, 0x25, 0xf4, 0xe7, 0xd4, 0x25, 0x5a, 0x13, 0x9a, 0xf1, 0x2c, 0x51, 0x1b, 0x75, 0x75,
-	0x34, 0xfd, 0xb9, 0x5f, 0x4d, 0x95, 0x63, 0xde, 0xed, 0xe3, 0x30, 0xc5, 0x96, 0xdc, 0x3f, 0x80,
-	0xf7, 0x1a, 0xf1, 0xff, 0x5f, 0x25, 0x3b, 0x54, 0x92, 0xea, 0x0a, 0xde, 0x13, 0x85, 0xf4, 0x9b,
-	0x6a, 0xe7, 0x65, 0xae, 0xc1, 0xb2, 0xe8, 0x0d, 0xc7, 0x4e, 0xb8, 0x87, 0xd6, 0x73, 0x90, 0xc8,
-	0xcc, 0x6a, 0x65, 0x47, 0xda, 0x2a, 0xa7, 0xb1, 0xff, 0xe5, 0xa0, 0xa9, 0xca, 0x17, 0x8a, 0x3f,
-	0x83, 0x05, 0xb1, 0x21, 0x90, 0x86, 0xc6, 0xda, 0x06, 0x5f, 0xc8, 0xbe, 0xe3, 0x17, 0x0f, 0x36,
-	0x84, 0xf7, 0x70, 0x6d, 0x26, 0xec, 0xd8, 0xa0, 0x72, 0x79, 0x76, 0x4e, 0xf2, 0xed, 0xcd, 0xe8,
-	0x10, 0x48, 0x5d, 0xb6, 0xdc, 0x85, 0xb0, 0x2c, 0xf7, 0x96, 0x40, 0x86, 0x40, 0x6a, 0xa1, 0x5d,
-	0x50, 0x88, 0xc0, 0x2e, 0xd8, 0xe2, 0xf6, 0x03, 0x5c, 0xeb, 0xa0, 0x15, 0x9f, 0x33, 0xd9, 0x8a,
-	0xcf, 0x1b, 0x7d, 0x5b, 0x19, 0xb9, 0x6f, 0x35, 0xf3, 0x48, 0xe7, 0x35, 0xfd, 0x40, 0x07, 0xdf,
-	0x01, 0x00, 0x00, 0xff, 0xff, 0x2c, 0x8d, 0x96, 0xcd, 0xb5, 0x03, 0x00, 0x00,
+	// 778 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0xff, 0x4e, 0xd3, 0x50,
+	0x14, 0x76, 0x63, 0x1b, 0xdd, 0x19, 0xfb, 0xd1, 0x8b, 0xb0, 0xda, 0x29, 0x59, 0x6a, 0xa2, 0x84,
+	0xe8, 0xa6, 0x93, 0x3f, 0x0c, 0x24, 0x26, 0x30, 0x58, 0x58, 0x60, 0x1b, 0x29, 0x43, 0x0d, 0xc1,
+	0x34, 0xdd, 0x7a, 0x19, 0xcd, 0xba, 0xb6, 0xb6, 0x77, 0x44, 0x30, 0xc4, 0xc4, 0x57, 0xf0, 0x95,
+	0x7c, 0x03, 0x5f, 0xc1, 0x07, 0xf0, 0x11, 0x4c, 0xef, 0x6d, 0x59, 0xe9, 0x30, 0x8e, 0xbf, 0xd6,
+	0xf3, 0x9d, 0x73, 0xbf, 0xf3, 0xe3, 0x9e, 0xef, 0x0e, 0xf8, 0x9e, 0x61, 0x0d, 0xec, 0x5e, 0xd5,
+	0xfb, 0xa9, 0xd8, 0x8e, 0x45, 0x2c, 0x94, 0xf0, 0xbe, 0xc5, 0xc7, 0x03, 0xcb, 0x1a, 0x18, 0xb8,
+	0xaa, 0xda, 0x7a, 0x55, 0x35, 0x4d, 0x8b, 0xa8, 0x44, 0xb7, 0x4c, 0x97, 0xc5, 0x88, 0x65, 0xdf,
+	0x4b, 0xad, 0xde, 0xf8, 0xac, 0x7a, 0xa6, 0x63, 0x43, 0x53, 0x46, 0xaa, 0x3b, 0x64, 0x11, 0xd2,
+	0xcf, 0x18, 0x24, 0xb6, 0x0d, 0x6b, 0x80, 0x72, 0x10, 0xd7, 0x35, 0x21, 0x56, 0x8e, 0xad, 0xa6,
+	0xe5, 0xb8, 0xae, 0xa1, 0x12, 0xa4, 0xd5, 0x31, 0x39, 0xb7, 0x1c, 0x45, 0xd7, 0x84, 0x38, 0x85,
+	0x39, 0x06, 0x34, 0x35, 0xf4, 0x10, 0x92, 0x44, 0x27, 0x06, 0x16, 0xe6, 0xa8, 0x83, 0x19, 0x48,
+	0x80, 0xf9, 0xbe, 0x65, 0x12, 0x6c, 0x12, 0x21, 0x41, 0xf1, 0xc0, 0x44, 0x1b, 0x90, 0xf3, 0x3f,
+	0x95, 0x33, 0xcb, 0x19, 0xa9, 0x44, 0x48, 0x96, 0x63, 0xab, 0xb9, 0xda, 0x62, 0x85, 0x36, 0x54,
+	0x67, 0xbe, 0x06, 0x75, 0xc9, 0xd9, 0x7e, 0xd8, 0x44, 0x4f, 0x21, 0xeb, 0x60, 0x53, 0xc3, 0x0e,
+	0xd6, 0x94, 0x73, 0x32, 0x32, 0x84, 0x14, 0xe5, 0x5e, 0x08, 0xc0, 0x3d, 0x32, 0x32, 0xa4, 0x53,
+	0xe0, 0xeb, 0x0e, 0x56, 0x09, 0xf6, 0x7a, 0x91, 0xf1, 0xe7, 0x31, 0x76, 0x09, 0x5a, 0x01, 0x3a,
+	0x23, 0xda, 0x54, 0xa6, 0x06, 0x2c, 0x17, 0x0d, 0xa0, 0x38, 0x7a, 0x0e, 0x79, 0x5d, 0xc3, 0x23,
+	0xdb, 0x22, 0xd8, 0xec, 0x5f, 0x2a, 0x43, 0x7c, 0xe9, 0x37, 0x9a, 0x0b, 0xc1, 0xfb, 0xf8, 0x52,
+	0x5a, 0x07, 0x14, 0x66, 0x77, 0x6d, 0xcb, 0x74, 0xf1, 0xff, 0xe8, 0xa5, 0x35, 0xc8, 0xcb, 0x58,
+	0xd5, 0xc2, 0x15, 0x15, 0x61, 0xde, 0x73, 0x29, 0x37, 0x93, 0x4e, 0x79, 0x66, 0x53, 0x93, 0x6a,
+	0x50, 0x98, 0xc4, 0xce, 0xc8, 0x6f, 0x03, 0x7f, 0x6c, 0x6b, 0xf7, 0xec, 0x79, 0x13, 0x32, 0x63,
+	0x7a, 0x88, 0x2e, 0x01, 0xed, 0x37, 0x53, 0x13, 0x2b, 0x6c, 0x4f, 0x2a, 0xc1, 0x9e, 0x54, 0x1a,
+	0xde, 0x9e, 0xb4, 0x54, 0x77, 0x28, 0x03, 0x0b, 0xf7, 0xbe, 0xbd, 0x39, 0x84, 0x33, 0xce, 0x58,
+	0xe7, 0x0b, 0xe0, 0x77, 0xb0, 0x81, 0x09, 0x9e, 0x69, 0x12, 0x2f, 0x01, 0x85, 0xa3, 0xfd, 0x1c,
+	0xff, 0x0c, 0xe7, 0x21, 0x7f, 0xa0, 0xbb, 0x24, 0x44, 0xed, 0xcd, 0x72, 0x02, 0xcd, 0x58, 0x63,
+	0x7b, 0x72, 0xc6, 0x0d, 0x4a, 0x2c, 0x41, 0xda, 0x56, 0x07, 0x58, 0x71, 0xf5, 0x2b, 0x4c, 0x0f,
+	0x26, 0x65, 0xce, 0x03, 0x8e, 0xf4, 0x2b, 0x8c, 0x9e, 0x00, 0x50, 0x27, 0xb1, 0x86, 0xd8, 0xf4,
+	0xd7, 0x86, 0x86, 0x77, 0x3d, 0x40, 0xfa, 0x04, 0x7c, 0x88, 0xcf, 0x2f, 0xa2, 0x0c, 0x49, 0x2f,
+	0x99, 0x2b, 0xc4, 0xca, 0x73, 0x91, 0x2a, 0x98, 0x03, 0x3d, 0x83, 0xbc, 0x89, 0xbf, 0x10, 0x65,
+	0x8a, 0x3a, 0xeb, 0xc1, 0x87, 0x37, 0xf4, 0xeb, 0x80, 0x64, 0xec, 0x12, 0xcb, 0xb9, 0xcf, 0xdd,
+	0x4b, 0x1d, 0x58, 0xbc, 0x75, 0x6a, 0xb6, 0xd9, 0x50, 0x59, 0xd3, 0xed, 0x67, 0xef, 0x00, 0x27,
+	0x07, 0xe6, 0x9a, 0x0c, 0xd9, 0x5b, 0xd2, 0x45, 0x2b, 0x20, 0xd6, 0x3b, 0xed, 0xee, 0x6e, 0xbb,
+	0xab, 0x34, 0x3a, 0x72, 0x6b, 0xab, 0xab, 0x1c, 0xb7, 0x8f, 0x0e, 0x77, 0xeb, 0xcd, 0x46, 0x73,
+	0x77, 0xa7, 0xf0, 0x00, 0xa5, 0x21, 0x79, 0x78, 0xb0, 0xd5, 0x6c, 0x17, 0x62, 0x68, 0x01, 0xb8,
+	0xd6, 0x96, 0xbc, 0xbf, 0xd3, 0xf9, 0xd0, 0x2e, 0xc4, 0x11, 0x07, 0x89, 0xbd, 0x6e, 0xeb, 0xa0,
+	0x30, 0x57, 0xfb, 0x93, 0x80, 0x8c, 0x97, 0xfc, 0x08, 0x3b, 0x17, 0x7a, 0x1f, 0xa3, 0x8f, 0x00,
+	0x13, 0xed, 0xa1, 0xa2, 0xff, 0x60, 0x44, 0xb5, 0x2e, 0x0a, 0xd3, 0x0e, 0xd6, 0x9e, 0x54, 0xfc,
+	0xfe, 0xeb, 0xf7, 0x8f, 0x38, 0xbf, 0xc1, 0x66, 0x90, 0xae, 0x5e, 0xbc, 0xae, 0xb2, 0x61, 0xbf,
+	0x07, 0x2e, 0xd0, 0x1c, 0x5a, 0x62, 0xc7, 0x23, 0x7a, 0x15, 0x97, 0xa3, 0xb0, 0xcf, 0x59, 0xa2,
+	0x9c, 0x4b, 0x68, 0xf1, 0x86, 0xad, 0xfa, 0xd5, 0xdf, 0xcf, 0x6b, 0xf4, 0x0d, 0x60, 0xa2, 0x92,
+	0xa0, 0xe2, 0x29, 0xa5, 0x8a, 0xc2, 0xb4, 0xc3, 0x67, 0x7f, 0x47, 0xd9, 0xdf, 0xb2, 0x8a, 0x4f,
+	0x4a, 0xec, 0x57, 0x8c, 0xe6, 0xaa, 0xe8, 0xda, 0x75, 0xed, 0x2e, 0x10, 0x9d, 0x02, 0x4c, 0x24,
+	0x14, 0x14, 0x30, 0x25, 0x41, 0x51, 0x98, 0x76, 0xdc, 0x6e, 0x6f, 0xed, 0xce, 0xf6, 0x36, 0x81,
+	0x0b, 0x56, 0x3b, 0x18, 0x5b, 0x44, 0x81, 0xe2, 0x72, 0x14, 0x66, 0xbc, 0xaf, 0x62, 0xa8, 0x03,
+	0xe9, 0x00, 0x75, 0x51, 0x24, 0x2c, 0x10, 0x9e, 0x58, 0x9c, 0xc2, 0xfd, 0xba, 0x78, 0x5a, 0x57,
+	0x06, 0x85, 0x2e, 0x71, 0x1b, 0x32, 0xa1, 0x9d, 0x46, 0x42, 0x70, 0x61, 0x51, 0x71, 0x88, 0x8f,
+	0xee, 0xf0, 0x30, 0xda, 0x6d, 0xee, 0x24, 0xc5, 0xfe, 0x5e, 0x7b, 0x29, 0xfa, 0x00, 0xbe, 0xf9,
+	0x3b, 0x00, 0xbc, 0x53, 0x49, 0x46, 0x6f, 0x07, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -526,6 +816,10 @@ type BlogServiceClient interface {
 	UpdateBlog(ctx context.Context, in *UpdateBlogRequest, opts ...grpc.CallOption) (*UpdateBlogResponse, error)
 	DeleteBlog(ctx context.Context, in *DeleteBlogRequest, opts ...grpc.CallOption) (*DeleteBlogResponse, error)
 	ListBlog(ctx context.Context, in *ListBlogRequest, opts ...grpc.CallOption) (BlogService_ListBlogClient, error)
+	// ListBlogs pages through the blogs in creation order, it backs GET /v1/blogs where streams are not an option
+	ListBlogs(ctx context.Context, in *ListBlogsRequest, opts ...grpc.CallOption) (*ListBlogsResponse, error)
+	// RestoreBlog writes a blog under its original id, creating it or replacing the blog with that id. Admins only.
+	RestoreBlog(ctx context.Context, in *RestoreBlogRequest, opts ...grpc.CallOption) (*RestoreBlogResponse, error)
 }
 
 type blogServiceClient struct {
@@ -604,6 +898,24 @@ func (x *blogServiceListBlogClient) Recv() (*ListBlogResponse, error) {
 	return m, nil
 }
 
+func (c *blogServiceClient) ListBlogs(ctx context.Context, in *ListBlogsRequest, opts ...grpc.CallOption) (*ListBlogsResponse, error) {
+	out := new(ListBlogsResponse)
+	err := c.cc.Invoke(ctx, "/blog.BlogService/ListBlogs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blogServiceClient) RestoreBlog(ctx context.Context, in *RestoreBlogRequest, opts ...grpc.CallOption) (*RestoreBlogResponse, error) {
+	out := new(RestoreBlogResponse)
+	err := c.cc.Invoke(ctx, "/blog.BlogService/RestoreBlog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BlogServiceServer is the server API for BlogService service.
 type BlogServiceServer interface {
 	CreateBlog(context.Context, *CreateBlogRequest) (*CreateBlogResponse, error)
@@ -611,6 +923,10 @@ type BlogServiceServer interface {
 	UpdateBlog(context.Context, *UpdateBlogRequest) (*UpdateBlogResponse, error)
 	DeleteBlog(context.Context, *DeleteBlogRequest) (*DeleteBlogResponse, error)
 	ListBlog(*ListBlogRequest, BlogService_ListBlogServer) error
+	// ListBlogs pages through the blogs in creation order, it backs GET /v1/blogs where streams are not an option
+	ListBlogs(context.Context, *ListBlogsRequest) (*ListBlogsResponse, error)
+	// RestoreBlog writes a blog under its original id, creating it or replacing the blog with that id. Admins only.
+	RestoreBlog(context.Context, *RestoreBlogRequest) (*RestoreBlogResponse, error)
 }
 
 func RegisterBlogServiceServer(s *grpc.Server, srv BlogServiceServer) {
@@ -710,6 +1026,42 @@ func (x *blogServiceListBlogServer) Send(m *ListBlogResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _BlogService_ListBlogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBlogsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlogServiceServer).ListBlogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blog.BlogService/ListBlogs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlogServiceServer).ListBlogs(ctx, req.(*ListBlogsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlogService_RestoreBlog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreBlogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlogServiceServer).RestoreBlog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blog.BlogService/RestoreBlog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlogServiceServer).RestoreBlog(ctx, req.(*RestoreBlogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _BlogService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "blog.BlogService",
 	HandlerType: (*BlogServiceServer)(nil),
@@ -730,6 +1082,14 @@ var _BlogService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteBlog",
 			Handler:    _BlogService_DeleteBlog_Handler,
 		},
+		{
+			MethodName: "ListBlogs",
+			Handler:    _BlogService_ListBlogs_Handler,
+		},
+		{
+			MethodName: "RestoreBlog",
+			Handler:    _BlogService_RestoreBlog_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -0,0 +1,10 @@
+package blogpb
+
+import (
+	_ "embed"
+)
+
+// OpenAPISpec is the OpenAPI v3 document of the REST gateway, generated from blog.proto by generate.sh
+//
+//go:embed openapi.yaml
+var OpenAPISpec []byte
@@ -1,3 +1,10 @@
 #!/bin/bash
 
-protoc blogpb/blog.proto --go_out=plugins=grpc:.
\ No newline at end of file
+# google/api/annotations.proto is vendored by grpc-gateway v1
+GOOGLEAPIS=${GOOGLEAPIS:-$(go env GOPATH)/src/github.com/grpc-ecosystem/grpc-gateway/third_party/googleapis}
+
+protoc -I. -I$GOOGLEAPIS blogpb/blog.proto --go_out=plugins=grpc:. --grpc-gateway_out=logtostderr=true:. \
+  --openapi_out=naming=proto,Mblogpb/blog.proto=github.com/k-yomo/blog_with_grpc/blogpb:blogpb
+protoc apikeypb/apikey.proto --go_out=plugins=grpc:.
+protoc -I. -I$GOOGLEAPIS backuppb/backup.proto \
+  --go_out=Mblogpb/blog.proto=github.com/k-yomo/blog_with_grpc/blogpb:.
\ No newline at end of file